@@ -0,0 +1,83 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterReloadPolicySpec defines the desired state of ClusterReloadPolicy
+type ClusterReloadPolicySpec struct {
+	PolicyRules `json:",inline"`
+
+	// NamespaceSelector selects the namespaces the policy applies to. Every
+	// namespace matches when unset.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// ClusterReloadPolicyStatus defines the observed state of ClusterReloadPolicy
+type ClusterReloadPolicyStatus struct {
+	// ObservedGeneration is the generation of the policy the matches were computed for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// MatchedNamespaces are the namespaces the policy currently selects.
+	// +optional
+	MatchedNamespaces []string `json:"matchedNamespaces,omitempty"`
+
+	// MatchedWorkloadCount is the number of workloads the policy currently selects.
+	// +optional
+	MatchedWorkloadCount int32 `json:"matchedWorkloadCount,omitempty"`
+
+	// MatchedWorkloads are the workloads the policy currently selects, up to
+	// the first 100 by namespace, kind and name.
+	// +optional
+	MatchedWorkloads []MatchedWorkload `json:"matchedWorkloads,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,shortName=crp
+// +kubebuilder:printcolumn:name="Strategy",type=string,JSONPath=`.spec.strategy`
+// +kubebuilder:printcolumn:name="Priority",type=integer,JSONPath=`.spec.priority`
+// +kubebuilder:printcolumn:name="Workloads",type=integer,JSONPath=`.status.matchedWorkloadCount`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterReloadPolicy is the Schema for the clusterreloadpolicies API.
+// It applies its rules in every namespace selected by its namespace selector,
+// with a lower precedence than the ReloadPolicies of the namespace.
+type ClusterReloadPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterReloadPolicySpec   `json:"spec,omitempty"`
+	Status ClusterReloadPolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterReloadPolicyList contains a list of ClusterReloadPolicy
+type ClusterReloadPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterReloadPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterReloadPolicy{}, &ClusterReloadPolicyList{})
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// SourceSelector selects the ConfigMaps or Secrets whose changes trigger reloads.
+type SourceSelector struct {
+	// Kind of the selected sources.
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	Kind string `json:"kind"`
+
+	// Names are glob patterns matched against the names of the sources,
+	// e.g. "app-*". Every name matches when empty.
+	// +optional
+	Names []string `json:"names,omitempty"`
+
+	// Selector selects the sources by label. Every source matches when unset.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// TargetSelector selects the workloads to reload.
+type TargetSelector struct {
+	// Kinds restricts the selected workloads to the given kinds, e.g.
+	// Deployment. Every kind matches when empty.
+	// +optional
+	Kinds []string `json:"kinds,omitempty"`
+
+	// Selector selects the workloads by label. Every workload matches when unset.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// MaintenanceWindow is a recurring period during which reloads may run.
+type MaintenanceWindow struct {
+	// Schedule is the cron expression of the opening of the window, e.g. "0 2 * * *".
+	Schedule string `json:"schedule"`
+
+	// Duration is how long the window stays open.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// StrategyParameters configure the in-place reload strategies.
+type StrategyParameters struct {
+	// HTTPPort is the numeric or named container port of the reload endpoint
+	// of the http strategy.
+	// +optional
+	HTTPPort *intstr.IntOrString `json:"httpPort,omitempty"`
+
+	// HTTPPath is the path of the reload endpoint, "/-/reload" by default.
+	// +optional
+	HTTPPath string `json:"httpPath,omitempty"`
+
+	// HTTPMethod is the method of the reload call, POST by default.
+	// +optional
+	HTTPMethod string `json:"httpMethod,omitempty"`
+
+	// HTTPHeaders are extra headers of the reload call.
+	// +optional
+	HTTPHeaders map[string]string `json:"httpHeaders,omitempty"`
+
+	// Command is the command the exec strategy runs in the container.
+	// +optional
+	Command []string `json:"command,omitempty"`
+
+	// Container is the container the exec and signal strategies run in,
+	// the first one by default.
+	// +optional
+	Container string `json:"container,omitempty"`
+
+	// Signal is the signal sent to PID 1 by the signal strategy, HUP by default.
+	// +optional
+	Signal string `json:"signal,omitempty"`
+}
+
+// PolicyRules are the reload rules shared by the policy kinds.
+type PolicyRules struct {
+	// Priority orders the policies of one kind selecting the same workload.
+	// Settings are taken from the policy with the highest priority that
+	// specifies them, ties broken by name. ReloadPolicies always take
+	// precedence over ClusterReloadPolicies.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// Sources select the ConfigMaps and Secrets whose changes reload the targets.
+	// +kubebuilder:validation:MinItems=1
+	Sources []SourceSelector `json:"sources"`
+
+	// Targets select the workloads to reload. A workload matches when any
+	// target selects it; every workload matches when empty.
+	// +optional
+	Targets []TargetSelector `json:"targets,omitempty"`
+
+	// Strategy is how pods pick up a change, unless the workload sets its own.
+	// +kubebuilder:validation:Enum=restart;http;exec;signal
+	// +optional
+	Strategy string `json:"strategy,omitempty"`
+
+	// Parameters configure the strategy of the policy. The annotations of a
+	// workload take precedence over them one by one.
+	// +optional
+	Parameters *StrategyParameters `json:"parameters,omitempty"`
+
+	// Debounce delays a reload until the sources stayed unchanged for this long.
+	// +optional
+	Debounce *metav1.Duration `json:"debounce,omitempty"`
+
+	// Cooldown is the minimum interval between two reloads of a workload,
+	// unless the workload sets its own.
+	// +optional
+	Cooldown *metav1.Duration `json:"cooldown,omitempty"`
+
+	// Windows restrict reloads to the given maintenance windows, replacing
+	// those of the namespace and the controller.
+	// +optional
+	Windows []MaintenanceWindow `json:"windows,omitempty"`
+}
+
+// ReloadPolicySpec defines the desired state of ReloadPolicy
+type ReloadPolicySpec struct {
+	PolicyRules `json:",inline"`
+}
+
+// MatchedWorkload is a workload selected by a policy.
+type MatchedWorkload struct {
+	// Namespace of the workload, for cluster-scoped policies.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+}
+
+// ReloadPolicyStatus defines the observed state of ReloadPolicy
+type ReloadPolicyStatus struct {
+	// ObservedGeneration is the generation of the policy the matches were computed for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// MatchedWorkloadCount is the number of workloads the policy currently selects.
+	// +optional
+	MatchedWorkloadCount int32 `json:"matchedWorkloadCount,omitempty"`
+
+	// MatchedWorkloads are the workloads the policy currently selects, up to
+	// the first 100 by namespace, kind and name.
+	// +optional
+	MatchedWorkloads []MatchedWorkload `json:"matchedWorkloads,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=rp
+// +kubebuilder:printcolumn:name="Strategy",type=string,JSONPath=`.spec.strategy`
+// +kubebuilder:printcolumn:name="Priority",type=integer,JSONPath=`.spec.priority`
+// +kubebuilder:printcolumn:name="Workloads",type=integer,JSONPath=`.status.matchedWorkloadCount`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ReloadPolicy is the Schema for the reloadpolicies API.
+// It reloads the workloads of its namespace selected by its targets when the
+// ConfigMaps or Secrets selected by its sources change, in addition to the
+// workloads opted in by annotation.
+type ReloadPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReloadPolicySpec   `json:"spec,omitempty"`
+	Status ReloadPolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReloadPolicyList contains a list of ReloadPolicy
+type ReloadPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReloadPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReloadPolicy{}, &ReloadPolicyList{})
+}
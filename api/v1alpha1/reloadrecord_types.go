@@ -0,0 +1,150 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReloadPhase is the progress of a reload.
+// +kubebuilder:validation:Enum=Pending;InProgress;Succeeded;RolledBack;Failed
+type ReloadPhase string
+
+const (
+	// ReloadPending means no target was reloaded yet, e.g. while waiting for
+	// a maintenance window.
+	ReloadPending ReloadPhase = "Pending"
+	// ReloadInProgress means some targets are reloaded or rolling out.
+	ReloadInProgress ReloadPhase = "InProgress"
+	// ReloadSucceeded means every target rolled out the change.
+	ReloadSucceeded ReloadPhase = "Succeeded"
+	// ReloadRolledBack means a target was rolled back to a previous pod template.
+	ReloadRolledBack ReloadPhase = "RolledBack"
+	// ReloadFailed means a target was deleted, put on hold or failed to roll out.
+	ReloadFailed ReloadPhase = "Failed"
+)
+
+// Done reports whether the phase is final.
+func (p ReloadPhase) Done() bool {
+	return p == ReloadSucceeded || p == ReloadRolledBack || p == ReloadFailed
+}
+
+// SourceReference identifies a ConfigMap or Secret in the namespace of the record.
+type SourceReference struct {
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// ReloadTarget is a workload reloaded because of the change.
+type ReloadTarget struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Hash is the dependency hash the workload is reloaded to.
+	Hash string `json:"hash"`
+	// Strategy is the reload strategy selected for the workload.
+	Strategy string `json:"strategy"`
+}
+
+// ReloadRecordSpec defines the desired state of ReloadRecord
+type ReloadRecordSpec struct {
+	// Source is the changed ConfigMap or Secret.
+	Source SourceReference `json:"source"`
+
+	// OldHash is the hash of the content of the source before the change.
+	// It is empty when the controller did not observe it.
+	// +optional
+	OldHash string `json:"oldHash,omitempty"`
+
+	// NewHash is the hash of the content of the source after the change.
+	// It is empty when the source was deleted.
+	// +optional
+	NewHash string `json:"newHash,omitempty"`
+
+	// ChangedKeys are the keys added, removed or modified by the change.
+	// +optional
+	ChangedKeys []string `json:"changedKeys,omitempty"`
+
+	// FieldManager is the field manager that last wrote the changed data of
+	// the source.
+	// +optional
+	FieldManager string `json:"fieldManager,omitempty"`
+
+	// Targets are the workloads reloaded because of the change.
+	Targets []ReloadTarget `json:"targets"`
+
+	// Timestamp is when the controller observed the change.
+	Timestamp metav1.Time `json:"timestamp"`
+}
+
+// TargetStatus is the progress of the reload of one target.
+type TargetStatus struct {
+	Kind  string      `json:"kind"`
+	Name  string      `json:"name"`
+	Phase ReloadPhase `json:"phase"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ReloadRecordStatus defines the observed state of ReloadRecord
+type ReloadRecordStatus struct {
+	// Phase summarizes the progress of the targets. A failed or rolled back
+	// target fails the record.
+	// +optional
+	Phase ReloadPhase `json:"phase,omitempty"`
+
+	// Targets is the progress of every target.
+	// +optional
+	Targets []TargetStatus `json:"targets,omitempty"`
+
+	// CompletionTime is when the record reached a final phase.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=rr
+// +kubebuilder:printcolumn:name="Kind",type=string,JSONPath=`.spec.source.kind`
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.source.name`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ReloadRecord is the Schema for the reloadrecords API.
+// The controller creates one for every change of a ConfigMap or Secret that
+// reloads workloads, tracks the rollout in its status and deletes it once
+// the configured retention elapsed.
+type ReloadRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReloadRecordSpec   `json:"spec,omitempty"`
+	Status ReloadRecordStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReloadRecordList contains a list of ReloadRecord
+type ReloadRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReloadRecord `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReloadRecord{}, &ReloadRecordList{})
+}
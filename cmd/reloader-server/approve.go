@@ -0,0 +1,117 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/approval"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+type approveFlagConfig struct {
+	namespace string
+	hash      string
+}
+
+// runApprove approves, or rejects, the change of a critical ConfigMap or
+// Secret waiting for approval. The approver is the authenticated user of the
+// kubeconfig, recorded by the admission webhook; without the webhook the
+// decision cannot be attributed and the command fails.
+//
+//	reloader-server approve configmap/app-config -n prod
+//	reloader-server reject secret/db-credentials -n prod
+func runApprove(args []string, reject bool) error {
+	verb := "approve"
+	if reject {
+		verb = "reject"
+	}
+	fs := flag.NewFlagSet(verb, flag.ExitOnError)
+	cfg := &approveFlagConfig{}
+	fs.StringVar(&cfg.namespace, "n", "default", "namespace of the source")
+	fs.StringVar(&cfg.namespace, "namespace", "default", "namespace of the source")
+	fs.StringVar(&cfg.hash, "hash", "", "content hash of the change, the pending one by default")
+
+	// source 는 flag 앞뒤 어디에나 올 수 있음
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	target := fs.Arg(0)
+	if fs.NArg() > 0 {
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return err
+		}
+	}
+	kind, name, err := parseSource(target)
+	if err != nil {
+		return err
+	}
+	kubeCfg, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(kubeCfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	src := workload.NewSource(kind)
+	if err := c.Get(ctx, client.ObjectKey{Namespace: cfg.namespace, Name: name}, src); err != nil {
+		return err
+	}
+	if !approval.Critical(src) {
+		return fmt.Errorf("%s %s/%s is not labelled %s=true", kind, cfg.namespace, name, annotation.Critical)
+	}
+	hash := cfg.hash
+	if hash == "" {
+		pending, ok := approval.GetPending(src)
+		if !ok {
+			return fmt.Errorf("%s %s/%s has no change waiting for approval", kind, cfg.namespace, name)
+		}
+		hash = pending.Hash
+	}
+
+	recorded, err := approval.Decide(ctx, c, src, approval.Decision{Hash: hash, Rejected: reject})
+	if err != nil {
+		return fmt.Errorf("%s %s/%s: %w", kind, cfg.namespace, name, err)
+	}
+	fmt.Printf("%s %s/%s change %s %sd by %s\n", kind, cfg.namespace, name, hash, verb, recorded.Approver)
+	return nil
+}
+
+// parseSource parses "configmap/name" or "secret/name".
+func parseSource(s string) (workload.SourceKind, string, error) {
+	kind, name, ok := strings.Cut(s, "/")
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("expected configmap/<name> or secret/<name>, got %q", s)
+	}
+	switch strings.ToLower(kind) {
+	case "configmap", "cm":
+		return workload.ConfigMap, name, nil
+	case "secret":
+		return workload.Secret, name, nil
+	}
+	return "", "", fmt.Errorf("unknown source kind %q, expected configmap or secret", kind)
+}
@@ -18,12 +18,13 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -34,8 +35,10 @@ import (
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	reloaderv1alpha1 "github.com/hotkimho/reloader-server/project/api/v1alpha1"
 	"github.com/hotkimho/reloader-server/project/internal/config"
 	"github.com/hotkimho/reloader-server/project/pkg/controller"
+	"github.com/hotkimho/reloader-server/project/pkg/webhook"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -57,11 +60,28 @@ type flagConfig struct {
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(reloaderv1alpha1.AddToScheme(scheme))
 
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "sidecar":
+			if err := runSidecar(os.Args[2:]); err != nil {
+				os.Exit(1)
+			}
+			return
+		case "approve", "reject":
+			if err := runApprove(os.Args[2:], os.Args[1] == "reject"); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	cfg := parseFlagConfig()
 
 	opts := zap.Options{
@@ -120,23 +140,33 @@ func startManager(flag *flagConfig, scheme *runtime.Scheme) error {
 		return err
 	}
 
-	cfg := config.NewConfig()
-	if err := yaml.Unmarshal([]byte(cm.Data[flag.configDataKey]), &cfg); err != nil {
+	cfg, err := config.Parse(cm.Data[flag.configDataKey])
+	if err != nil {
 		setupLog.Error(err, "unable to unmarshal configmap data")
 		return err
 	}
 	cfg.Manager.SetTLS()
 
+	store := config.NewStore(cfg)
+	store.Key = types.NamespacedName{Namespace: flag.configNamespace, Name: flag.configName}
+	store.DataKey = flag.configDataKey
+
 	mgr, err := ctrl.NewManager(kubeCfg, cfg.Manager.ConvertCtrlOption(scheme))
 	if err != nil {
 		setupLog.Error(err, "unable to create manager")
 		return err
 	}
 
-	if err = controller.SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to set up alert rule controller")
+	if err = controller.SetupWithManager(mgr, store); err != nil {
+		setupLog.Error(err, "unable to set up reload controller")
 		return err
 	}
+	if cfg.Manager.EnableWebhooks {
+		if err = webhook.SetupWithManager(mgr, store); err != nil {
+			setupLog.Error(err, "unable to set up webhooks")
+			return err
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
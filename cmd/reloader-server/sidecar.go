@@ -0,0 +1,115 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"net/http"
+	"strings"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/hotkimho/reloader-server/project/pkg/sidecar"
+)
+
+type sidecarFlagConfig struct {
+	watchDirs  string
+	debounce   time.Duration
+	httpURL    string
+	httpMethod string
+	timeout    time.Duration
+	process    string
+	signal     string
+	command    string
+}
+
+// runSidecar watches mounted ConfigMap/Secret directories and reloads the
+// application next to it, without any access to the API server.
+//
+//	reloader-server sidecar --watch-dir=/etc/app --http-url=http://localhost:9090/-/reload
+func runSidecar(args []string) error {
+	fs := flag.NewFlagSet("sidecar", flag.ExitOnError)
+	cfg := &sidecarFlagConfig{}
+	fs.StringVar(&cfg.watchDirs, "watch-dir", "", "comma separated directories to watch")
+	fs.DurationVar(&cfg.debounce, "debounce", time.Second, "time to wait for a burst of changes to settle")
+	fs.StringVar(&cfg.httpURL, "http-url", "", "reload by calling this URL, e.g. http://localhost:9090/-/reload")
+	fs.StringVar(&cfg.httpMethod, "http-method", http.MethodPost, "method of the reload call")
+	fs.DurationVar(&cfg.timeout, "timeout", 10*time.Second, "timeout of the reload call")
+	fs.StringVar(&cfg.process, "process", "", "reload by signalling the process with this name (requires shareProcessNamespace)")
+	fs.StringVar(&cfg.signal, "signal", "HUP", "signal sent to --process")
+	fs.StringVar(&cfg.command, "command", "", "reload by running this command")
+
+	opts := zap.Options{}
+	opts.BindFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	logger := ctrl.Log.WithName("sidecar")
+
+	trigger, err := cfg.trigger()
+	if err != nil {
+		logger.Error(err, "invalid sidecar flags")
+		return err
+	}
+
+	watcher := &sidecar.Watcher{
+		Dirs:     strings.Split(cfg.watchDirs, ","),
+		Debounce: cfg.debounce,
+		Trigger:  trigger,
+		Log:      logger,
+	}
+	if err := watcher.Run(ctrl.SetupSignalHandler()); err != nil {
+		logger.Error(err, "problem running sidecar")
+		return err
+	}
+	return nil
+}
+
+// trigger returns the reload trigger selected by the flags, exactly one must be set.
+func (c *sidecarFlagConfig) trigger() (sidecar.Trigger, error) {
+	if c.watchDirs == "" {
+		return nil, errors.New("--watch-dir is required")
+	}
+
+	var triggers []sidecar.Trigger
+	if c.httpURL != "" {
+		triggers = append(triggers, &sidecar.HTTPTrigger{
+			URL:    c.httpURL,
+			Method: c.httpMethod,
+			Client: &http.Client{Timeout: c.timeout},
+		})
+	}
+	if c.process != "" {
+		t, err := sidecar.NewSignalTrigger(c.process, c.signal)
+		if err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, t)
+	}
+	if c.command != "" {
+		triggers = append(triggers, &sidecar.CommandTrigger{Command: strings.Fields(c.command)})
+	}
+
+	if len(triggers) != 1 {
+		return nil, errors.New("exactly one of --http-url, --process or --command is required")
+	}
+	return triggers[0], nil
+}
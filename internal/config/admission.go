@@ -0,0 +1,13 @@
+package config
+
+// ConfigMap, Secret 변경을 admission 단계에서 검사하는 설정
+type AdmissionConfig struct {
+	// 0 보다 크면 restart 될 pod 수가 이 값을 넘는 변경을 거부함
+	// 변경하는 ConfigMap, Secret 에 override annotation 이 있으면 허용
+	MaxRestartedPods int `json:"maxRestartedPods"`
+}
+
+// Default 값으로 AdmissionConfig 생성
+func newAdmissionConfig() *AdmissionConfig {
+	return &AdmissionConfig{}
+}
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// critical label 이 붙은 ConfigMap/Secret 변경의 승인 설정
+type ApprovalConfig struct {
+	// 이 시간 안에 승인되지 않은 변경은 만료되어 rollout 되지 않음
+	Timeout metav1.Duration `json:"timeout"`
+}
+
+// Default 값으로 ApprovalConfig 생성
+func newApprovalConfig() *ApprovalConfig {
+	return &ApprovalConfig{
+		Timeout: metav1.Duration{Duration: 24 * time.Hour},
+	}
+}
@@ -1,11 +1,30 @@
 package config
 
 type Config struct {
-	Manager *ManagerConfig `json:"manager"`
+	Manager  *ManagerConfig  `json:"manager"`
+	Reload   *ReloadConfig   `json:"reload"`
+	Windows  *WindowConfig   `json:"windows"`
+	Strategy *StrategyConfig `json:"strategy"`
+	Records  *RecordConfig   `json:"records"`
+	// ConfigMap, Secret 변경의 영향 범위 검사
+	Admission *AdmissionConfig `json:"admission"`
+	// critical source 변경 승인
+	Approval *ApprovalConfig `json:"approval"`
+	// 외부 알림
+	Notifications *NotificationConfig `json:"notifications"`
+	// 기본 지원(Deployment, StatefulSet, DaemonSet) 외에 reload 할 워크로드
+	Workloads []WorkloadConfig `json:"workloads,omitempty"`
 }
 
 func NewConfig() *Config {
 	return &Config{
-		Manager: newManagerConfig(),
+		Manager:       newManagerConfig(),
+		Reload:        newReloadConfig(),
+		Windows:       newWindowConfig(),
+		Strategy:      newStrategyConfig(),
+		Records:       newRecordConfig(),
+		Admission:     newAdmissionConfig(),
+		Approval:      newApprovalConfig(),
+		Notifications: newNotificationConfig(),
 	}
 }
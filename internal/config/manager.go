@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -16,6 +17,7 @@ type ManagerConfig struct {
 	MetricsAddr          string         `json:"metricsAddr"`
 	SecureMetrics        bool           `json:"secureMetrics"`
 	EnableHTTP2          bool           `json:"enableHTTP2"`
+	EnableWebhooks       bool           `json:"enableWebhooks"`
 	Metrics              server.Options `json:"-"`
 	WebhookServer        webhook.Server `json:"-"`
 }
@@ -28,6 +30,7 @@ func newManagerConfig() *ManagerConfig {
 		EnableLeaderElection: false,
 		SecureMetrics:        true,
 		EnableHTTP2:          false,
+		EnableWebhooks:       true,
 		LeaderElectionID:     "dd36baba.accordions.edu",
 	}
 }
@@ -66,5 +69,7 @@ func (c *ManagerConfig) ConvertCtrlOption(scheme *runtime.Scheme) ctrl.Options {
 		HealthProbeBindAddress: c.ProbeAddr,
 		LeaderElection:         c.EnableLeaderElection,
 		LeaderElectionID:       c.LeaderElectionID,
+		// generic 워크로드도 cache 에서 읽도록 설정
+		Client: client.Options{Cache: &client.CacheOptions{Unstructured: true}},
 	}
 }
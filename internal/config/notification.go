@@ -0,0 +1,21 @@
+package config
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// 승인 요청, 거절 등을 외부로 알리는 설정
+type NotificationConfig struct {
+	// 알림을 JSON 으로 POST 할 URL, 비어 있으면 알림을 보내지 않음
+	WebhookURL string          `json:"webhookURL"`
+	Timeout    metav1.Duration `json:"timeout"`
+}
+
+// Default 값으로 NotificationConfig 생성
+func newNotificationConfig() *NotificationConfig {
+	return &NotificationConfig{
+		Timeout: metav1.Duration{Duration: 10 * time.Second},
+	}
+}
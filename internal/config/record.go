@@ -0,0 +1,23 @@
+package config
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// reload 마다 생성하는 ReloadRecord 설정
+type RecordConfig struct {
+	// false 면 ReloadRecord 를 만들지 않음
+	Enabled bool `json:"enabled"`
+	// 변경을 감지한 시점부터 이 시간이 지나면 ReloadRecord 삭제
+	Retention metav1.Duration `json:"retention"`
+}
+
+// Default 값으로 RecordConfig 생성
+func newRecordConfig() *RecordConfig {
+	return &RecordConfig{
+		Enabled:   true,
+		Retention: metav1.Duration{Duration: 7 * 24 * time.Hour},
+	}
+}
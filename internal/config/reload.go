@@ -0,0 +1,52 @@
+package config
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ReloadConfig struct {
+	// 워크로드별 최소 reload 간격
+	Cooldown metav1.Duration `json:"cooldown"`
+	// FlapWindow 안에서 FlapThreshold 번 reload 되면 hold 처리, 0 이면 비활성화
+	FlapThreshold int             `json:"flapThreshold"`
+	FlapWindow    metav1.Duration `json:"flapWindow"`
+	// true 면 optional 로 참조하는 ConfigMap/Secret 이 삭제되어도 reload 하지 않음
+	// required source 삭제는 항상 무시, 생성되는 경우는 항상 reload
+	IgnoreDelete bool `json:"ignoreDelete"`
+	// true 면 모든 자동 reload 를 멈추고 pending 으로 기록
+	Paused bool `json:"paused"`
+	// true 면 pause 해제 후 pending reload 를 바로 적용하지 않고 승인을 기다림
+	ResumeApproval bool `json:"resumeApproval"`
+	// 주기적 restart 가 한꺼번에 몰리지 않도록 워크로드마다 더하는 최대 지연
+	RestartJitter metav1.Duration `json:"restartJitter"`
+	// true 면 reload 대상 워크로드가 참조하는 ConfigMap/Secret 에 finalizer 를 달아 삭제를 막음
+	// false 로 바꾸면 각 source 의 다음 reconcile 에서 finalizer 를 제거함
+	// controller 를 uninstall 하기 전에 false 로 바꿔 finalizer 를 정리해야 source 와 namespace 삭제가 막히지 않음
+	ProtectInUse bool `json:"protectInUse"`
+	// dormant 였던 워크로드가 다시 실행된 뒤 새 설정의 pod 가 ready 되기를 기다리는 최대 시간
+	DeferredTimeout metav1.Duration `json:"deferredTimeout"`
+	// 이 field manager 들이 data 를 바꾼 경우 reload 하지 않음, glob 패턴 (e.g. "*-operator")
+	IgnoreManagers []string `json:"ignoreManagers,omitempty"`
+	// app.kubernetes.io/managed-by label 이 이 값들인 ConfigMap/Secret 의 변경은 reload 하지 않음
+	IgnoreManagedBy []string `json:"ignoreManagedBy,omitempty"`
+	// reloader_source_changes_total 의 manager label 로 그대로 쓰는 field manager
+	// 나머지는 cardinality 를 제한하기 위해 "other" 로 묶음
+	MetricManagers []string `json:"metricManagers,omitempty"`
+}
+
+// Default 값으로 ReloadConfig 생성
+func newReloadConfig() *ReloadConfig {
+	return &ReloadConfig{
+		Cooldown:        metav1.Duration{Duration: 30 * time.Second},
+		FlapThreshold:   5,
+		FlapWindow:      metav1.Duration{Duration: 10 * time.Minute},
+		RestartJitter:   metav1.Duration{Duration: 5 * time.Minute},
+		DeferredTimeout: metav1.Duration{Duration: 30 * time.Minute},
+		MetricManagers: []string{
+			"kubectl", "kubectl-client-side-apply", "kubectl-create", "kubectl-edit", "kubectl-patch",
+			"kubectl-replace", "kubectl-set", "helm", "argocd-controller", "kustomize-controller", "helm-controller",
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// 실행 중에 다시 읽어들일 수 있는 Config 보관소
+// Manager 설정은 시작할 때만 적용되고 나머지는 ConfigMap 이 바뀌면 바로 반영됨
+type Store struct {
+	// 설정이 저장된 ConfigMap 과 data key
+	Key     types.NamespacedName
+	DataKey string
+
+	current atomic.Pointer[Config]
+}
+
+func NewStore(cfg *Config) *Store {
+	s := &Store{}
+	s.current.Store(cfg)
+	return s
+}
+
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// ConfigMap 의 설정으로 교체하고 이전 설정을 반환
+func (s *Store) Update(cm *corev1.ConfigMap) (*Config, error) {
+	cfg, err := Parse(cm.Data[s.DataKey])
+	if err != nil {
+		return nil, err
+	}
+	// Manager 설정은 재시작 전까지 바뀌지 않음
+	cfg.Manager = s.Get().Manager
+	return s.current.Swap(cfg), nil
+}
+
+// Default 값 위에 data 를 덮어써서 Config 생성
+func Parse(data string) (*Config, error) {
+	cfg := NewConfig()
+	if err := yaml.Unmarshal([]byte(data), cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
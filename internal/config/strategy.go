@@ -0,0 +1,26 @@
+package config
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// in-place reload strategy (http 등) 설정
+type StrategyConfig struct {
+	// kubelet 이 마운트된 볼륨을 갱신할 때까지 기다리는 시간
+	SyncDelay metav1.Duration `json:"syncDelay"`
+	// SyncDelay 이후에도 파일이 갱신되지 않으면 이 시간까지 재시도한 뒤 restart
+	SyncTimeout metav1.Duration `json:"syncTimeout"`
+	// pod 하나를 reload 하는 데 허용되는 시간
+	Timeout metav1.Duration `json:"timeout"`
+}
+
+// Default 값으로 StrategyConfig 생성
+func newStrategyConfig() *StrategyConfig {
+	return &StrategyConfig{
+		SyncDelay:   metav1.Duration{Duration: 90 * time.Second},
+		SyncTimeout: metav1.Duration{Duration: 3 * time.Minute},
+		Timeout:     metav1.Duration{Duration: 10 * time.Second},
+	}
+}
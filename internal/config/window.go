@@ -0,0 +1,31 @@
+package config
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// reload 가 허용되는 시간대와 금지 기간
+// namespace annotation 으로 덮어쓸 수 있음
+type WindowConfig struct {
+	// 비어 있으면 항상 허용
+	Allowed []MaintenanceWindow `json:"allowed,omitempty"`
+	Freezes []FreezePeriod      `json:"freezes,omitempty"`
+}
+
+// Schedule 에 열려서 Duration 동안 유지되는 시간대
+// e.g. {"schedule": "CRON_TZ=Asia/Seoul 0 2 * * *", "duration": "2h"}
+type MaintenanceWindow struct {
+	Schedule string          `json:"schedule"`
+	Duration metav1.Duration `json:"duration"`
+}
+
+// [Start, End) 동안 reload 금지
+type FreezePeriod struct {
+	Start metav1.Time `json:"start"`
+	End   metav1.Time `json:"end"`
+}
+
+// Default 값으로 WindowConfig 생성
+func newWindowConfig() *WindowConfig {
+	return &WindowConfig{}
+}
@@ -0,0 +1,14 @@
+package config
+
+// unstructured 로 다루는 워크로드 종류 (e.g. Argo Rollouts, OpenShift DeploymentConfig)
+// 컨트롤러 시작 시에만 적용됨
+type WorkloadConfig struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+	// pod template 의 JSON path, e.g. ".spec.template"
+	TemplatePath string `json:"templatePath"`
+	// pod selector 의 JSON path, 기본값 ".spec.selector"
+	// in-place reload strategy 로 pod 를 찾을 때 사용
+	SelectorPath string `json:"selectorPath,omitempty"`
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package annotation holds the annotation keys understood by the reloader.
+package annotation
+
+const Prefix = "reloader.accordions.edu/"
+
+// Workload annotations
+const (
+	// Auto opts a workload in to reloads when any referenced ConfigMap or Secret changes.
+	Auto = Prefix + "auto"
+	// ConfigMapKeys narrows ConfigMaps consumed as a whole to the listed keys,
+	// as "name:key,name:key".
+	ConfigMapKeys = Prefix + "configmap-keys"
+	// SecretKeys is the Secret counterpart of ConfigMapKeys.
+	SecretKeys = Prefix + "secret-keys"
+	// IgnoreDelete set to "true" keeps the workload running when an optional
+	// source it consumes is deleted; "false" reloads it. Defaults to the
+	// controller config. The deletion of a required source never reloads.
+	IgnoreDelete = Prefix + "ignore-delete"
+	// ConfigHash is the dependency hash the workload was last reloaded with.
+	// It is set on both the workload and its pod template.
+	ConfigHash = Prefix + "config-hash"
+	// StaticHash is the hash of the keys consumed through env or subPath the
+	// workload was last reloaded with. A change of those keys always restarts.
+	StaticHash = Prefix + "static-hash"
+	// ObservedSources records the key hashes of the sources the workload
+	// consumes as last observed by the controller, as JSON keyed by
+	// "Kind/name", so that source changes are detected and recorded across
+	// controller restarts without writing to the sources themselves.
+	ObservedSources = Prefix + "observed-sources"
+	// ReloadHistory keeps the unix timestamps of recent reloads for flap detection.
+	ReloadHistory = Prefix + "reload-history"
+	// Cooldown overrides the minimum interval between two reloads, e.g. "5m".
+	Cooldown = Prefix + "cooldown"
+	// Hold suspends automatic reloads. It is set by the flap detector and
+	// removed by an operator once the source of the churn is fixed.
+	Hold = Prefix + "hold"
+	// Strategy selects how pods pick up a change: "restart" (default), "http",
+	// "exec" or "signal".
+	Strategy = Prefix + "strategy"
+	// HTTPPort is the numeric or named container port of the reload endpoint.
+	HTTPPort = Prefix + "http-port"
+	// HTTPPath is the path of the reload endpoint, "/-/reload" by default.
+	HTTPPath = Prefix + "http-path"
+	// HTTPMethod is the method of the reload call, POST by default.
+	HTTPMethod = Prefix + "http-method"
+	// HTTPHeaders are extra headers of the reload call, as a JSON object.
+	HTTPHeaders = Prefix + "http-headers"
+	// ExecCommand is the command of the exec strategy, as a JSON array or a
+	// whitespace separated string, e.g. "nginx -s reload".
+	ExecCommand = Prefix + "exec-command"
+	// ExecContainer is the container the exec and signal strategies run in.
+	// Defaults to the kubectl default container, or the first container.
+	ExecContainer = Prefix + "exec-container"
+	// Signal is the signal sent to PID 1 by the signal strategy, HUP by default.
+	Signal = Prefix + "signal"
+	// SyncDelay overrides how long to wait for the kubelet to update mounted
+	// volumes before an in-place reload, e.g. "90s".
+	SyncDelay = Prefix + "sync-delay"
+	// SyncingReload records an in-place reload waiting for the kubelet sync.
+	SyncingReload = Prefix + "syncing-reload"
+	// PendingReload records a reload queued until it is allowed to run.
+	PendingReload = Prefix + "pending-reload"
+	// Approve set to "true" releases a reload held back for approval.
+	Approve = Prefix + "approve"
+	// RestartSchedule reloads the workload periodically, on a cron schedule,
+	// e.g. "0 3 * * *", or at an interval, e.g. "24h".
+	RestartSchedule = Prefix + "restart-schedule"
+	// RestartJitter overrides the maximum delay spreading the scheduled
+	// reloads of workloads sharing a schedule, e.g. "10m".
+	RestartJitter = Prefix + "restart-jitter"
+	// ScheduledRestart records the last and next scheduled reload.
+	ScheduledRestart = Prefix + "scheduled-restart"
+	// RestartedAt is set on the pod template when the workload is restarted.
+	RestartedAt = Prefix + "restarted-at"
+	// DeferredReload records the hash stamped on a paused or scaled to zero
+	// workload, to be confirmed on its pods once it runs again.
+	DeferredReload = Prefix + "deferred-reload"
+	// DeferredWaiting records since when a workload with a deferred reload
+	// runs again and waits for pods with the recorded configuration.
+	DeferredWaiting = Prefix + "deferred-waiting"
+	// RecreatePendingJobs set to "true" on a CronJob deletes the Jobs whose
+	// pods have not started yet and recreates them from the updated job template.
+	RecreatePendingJobs = Prefix + "recreate-pending-jobs"
+)
+
+// Source annotations and labels
+const (
+	// OverrideBlastRadius set to "true" by an update of a ConfigMap or Secret
+	// admits that change even if it restarts more pods than the admission
+	// threshold allows. An annotation left from an earlier update admits nothing.
+	OverrideBlastRadius = Prefix + "override-blast-radius"
+	// Critical is a label; set to "true" on a ConfigMap or Secret, its
+	// changes are staged until approved.
+	Critical = Prefix + "critical"
+	// ApprovedHash is the content hash of the source last approved for
+	// rollout. Workloads are not reloaded while the content differs.
+	ApprovedHash = Prefix + "approved-hash"
+	// PendingApproval records the change waiting for approval, as JSON.
+	PendingApproval = Prefix + "pending-approval"
+	// Approval is the decision on the pending change, as JSON, e.g.
+	// {"hash":"...","approver":"alice"}, or with "rejected":true. The approver
+	// is set at admission to the user making the decision, so the mutating
+	// webhook is required; decisions without an approver are ignored.
+	Approval = Prefix + "approval"
+	// ChangedBy is the user who last changed the content of a critical
+	// source, set at admission. Its changes cannot be approved by that user.
+	ChangedBy = Prefix + "changed-by"
+	// IgnoreManagers lists field managers, as comma separated glob patterns,
+	// whose changes to the source never reload workloads, in addition to the
+	// ones of the controller config. Patterns matching any manager, e.g. "*",
+	// are disregarded.
+	IgnoreManagers = Prefix + "ignore-managers"
+)
+
+// Namespace annotations
+const (
+	// Paused set to "true" stops automatic reloads in the namespace.
+	Paused = Prefix + "paused"
+	// Windows overrides the allowed maintenance windows of the namespace,
+	// as a JSON list, e.g. [{"schedule":"0 2 * * *","duration":"2h"}].
+	Windows = Prefix + "windows"
+	// Freezes overrides the change freezes of the namespace, as a JSON list,
+	// e.g. [{"start":"2024-12-20T00:00:00Z","end":"2025-01-03T00:00:00Z"}].
+	Freezes = Prefix + "freezes"
+)
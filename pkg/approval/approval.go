@@ -0,0 +1,126 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package approval holds the state of the changes to critical ConfigMaps and
+// Secrets waiting for approval, shared by the controller and the CLI.
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+)
+
+// Outcomes of a pending change that will not be rolled out.
+const (
+	Rejected = "Rejected"
+	Expired  = "Expired"
+)
+
+// Pending is a change to a critical source waiting for approval, recorded by
+// the controller in the pending approval annotation of the source.
+type Pending struct {
+	// Hash is the content hash of the change.
+	Hash     string    `json:"hash"`
+	Since    time.Time `json:"since"`
+	Deadline time.Time `json:"deadline"`
+	// Outcome is set once the change was rejected or expired.
+	Outcome string `json:"outcome,omitempty"`
+	// Manager is the field manager that made the change, when known.
+	Manager string `json:"manager,omitempty"`
+}
+
+// Decision is the verdict on a change, set by an operator in the approval
+// annotation of the source.
+type Decision struct {
+	// Hash is the content hash of the change the decision is about.
+	Hash     string `json:"hash"`
+	Approver string `json:"approver"`
+	Rejected bool   `json:"rejected,omitempty"`
+}
+
+// Critical reports whether changes to src must be approved.
+func Critical(src client.Object) bool {
+	return src.GetLabels()[annotation.Critical] == "true"
+}
+
+// GetPending returns the change of src waiting for approval.
+func GetPending(src client.Object) (Pending, bool) {
+	var p Pending
+	return p, get(src, annotation.PendingApproval, &p)
+}
+
+// GetDecision returns the decision of an operator on a change of src.
+func GetDecision(src client.Object) (Decision, bool) {
+	var d Decision
+	return d, get(src, annotation.Approval, &d)
+}
+
+func get(src client.Object, key string, v any) bool {
+	value, ok := src.GetAnnotations()[key]
+	return ok && json.Unmarshal([]byte(value), v) == nil
+}
+
+// Set stores v as JSON in the annotation key of src.
+func Set(src client.Object, key string, v any) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	annotations := src.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[key] = string(value)
+	src.SetAnnotations(annotations)
+	return nil
+}
+
+// ErrNoApprover is returned by Decide when no approver was recorded, that is
+// when the admission webhook does not handle the source.
+var ErrNoApprover = errors.New("no approver was recorded, the mutating webhook of reloader-server must be installed and select the source")
+
+// Decide records decision on src and returns it with the approver recorded
+// by the admission webhook. The controller ignores decisions without an
+// approver, so without the webhook the decision is removed again and
+// ErrNoApprover returned.
+func Decide(ctx context.Context, c client.Client, src client.Object, decision Decision) (Decision, error) {
+	patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+	decision.Approver = ""
+	if err := Set(src, annotation.Approval, decision); err != nil {
+		return Decision{}, err
+	}
+	if err := c.Patch(ctx, src, patch); err != nil {
+		return Decision{}, err
+	}
+	if recorded, ok := GetDecision(src); ok && recorded.Approver != "" {
+		return recorded, nil
+	}
+
+	patch = client.MergeFrom(src.DeepCopyObject().(client.Object))
+	annotations := src.GetAnnotations()
+	delete(annotations, annotation.Approval)
+	src.SetAnnotations(annotations)
+	if err := c.Patch(ctx, src, patch); err != nil {
+		return Decision{}, err
+	}
+	return Decision{}, ErrNoApprover
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/approval"
+)
+
+var _ = Describe("Decide", func() {
+	var (
+		ctx context.Context
+		cm  *corev1.ConfigMap
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name: "app", Namespace: "default", Labels: map[string]string{annotation.Critical: "true"},
+		}}
+	})
+
+	It("records the approver set by the webhook", func() {
+		// webhook 처럼 요청한 사용자를 approver 로 기록
+		c := fake.NewClientBuilder().WithObjects(cm).WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				if d, ok := approval.GetDecision(obj); ok && d.Approver == "" {
+					d.Approver = "alice"
+					Expect(approval.Set(obj, annotation.Approval, d)).To(Succeed())
+				}
+				return c.Patch(ctx, obj, client.Merge, opts...)
+			},
+		}).Build()
+
+		recorded, err := approval.Decide(ctx, c, cm, approval.Decision{Hash: "abc"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorded).To(Equal(approval.Decision{Hash: "abc", Approver: "alice"}))
+	})
+
+	It("fails and withdraws the decision without the webhook", func() {
+		c := fake.NewClientBuilder().WithObjects(cm).Build()
+
+		_, err := approval.Decide(ctx, c, cm, approval.Decision{Hash: "abc"})
+		Expect(err).To(MatchError(approval.ErrNoApprover))
+		got := &corev1.ConfigMap{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(cm), got)).To(Succeed())
+		Expect(got.Annotations).NotTo(HaveKey(annotation.Approval))
+	})
+})
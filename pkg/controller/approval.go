@@ -0,0 +1,205 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/approval"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// reasonSourceApproval is the reason of a reload waiting for the change to a
+// critical source to be approved.
+func reasonSourceApproval(source string) string {
+	return "awaiting approval of the change to " + source
+}
+
+// reviewChange stages the changes to a critical source until an operator
+// other than their author approves them, and returns how long is left until
+// the pending change expires. The first content of a critical source needs
+// approval as well. The attribution of the change is only known when it was
+// just observed, later reviews use the one recorded on the pending change.
+func (r *reloader) reviewChange(ctx context.Context, kind workload.SourceKind, src client.Object, hash string,
+	attribution workload.Attribution, now time.Time) (time.Duration, error) {
+	if !approval.Critical(src) {
+		return 0, nil
+	}
+	annotations := src.GetAnnotations()
+	patch := client.MergeFrom(src.DeepCopyObject().(client.Object))
+	if annotations[annotation.ApprovedHash] == hash {
+		_, pending := annotations[annotation.PendingApproval]
+		_, decided := annotations[annotation.Approval]
+		if !pending && !decided {
+			return 0, nil
+		}
+		// 승인된 내용으로 되돌아온 경우
+		removeAnnotation(src, annotation.PendingApproval)
+		removeAnnotation(src, annotation.Approval)
+		return 0, r.client.Patch(ctx, src, patch)
+	}
+
+	pending, hasPending := approval.GetPending(src)
+	if hasPending && pending.Hash != hash {
+		hasPending = false
+	}
+	if attribution.Manager == "" && hasPending {
+		attribution.Manager = pending.Manager
+	}
+	change := fmt.Sprintf("%s %s/%s change %s", kind, src.GetNamespace(), src.GetName(), shortHash(hash))
+	if attribution.Manager != "" {
+		change += " by " + attribution.Manager
+	}
+
+	if decision, ok := approval.GetDecision(src); ok && decision.Hash == hash {
+		removeAnnotation(src, annotation.Approval)
+		details := changeDetails(hash, attribution, "approver", decision.Approver)
+		author := annotations[annotation.ChangedBy]
+		switch {
+		case !decision.Rejected && (decision.Approver == "" || decision.Approver == author):
+			if err := r.client.Patch(ctx, src, patch); err != nil {
+				return 0, err
+			}
+			r.announce(ctx, src, string(kind), corev1.EventTypeWarning, "ApprovalIgnored", fmt.Sprintf(
+				"Ignoring the approval of %s by %q: changes must be approved by an authenticated user other than their author",
+				change, decision.Approver), details)
+		case hasPending && pending.Outcome == approval.Expired && !decision.Rejected:
+			if err := r.client.Patch(ctx, src, patch); err != nil {
+				return 0, err
+			}
+			r.announce(ctx, src, string(kind), corev1.EventTypeWarning, "ApprovalIgnored", fmt.Sprintf(
+				"Ignoring the approval of %s by %s: it expired at %s, remove the %s annotation to request a new approval",
+				change, decision.Approver, pending.Deadline.Format(time.RFC3339), annotation.PendingApproval), details)
+		case decision.Rejected:
+			if !hasPending {
+				pending = approval.Pending{Hash: hash, Since: now.UTC().Truncate(time.Second), Deadline: now.UTC().Truncate(time.Second)}
+			}
+			pending.Outcome = approval.Rejected
+			if err := approval.Set(src, annotation.PendingApproval, pending); err != nil {
+				return 0, err
+			}
+			if err := r.client.Patch(ctx, src, patch); err != nil {
+				return 0, err
+			}
+			r.announce(ctx, src, string(kind), corev1.EventTypeWarning, "ChangeRejected",
+				fmt.Sprintf("%s was rejected by %s and will not be rolled out", change, decision.Approver), details)
+		default:
+			setAnnotation(src, annotation.ApprovedHash, hash)
+			removeAnnotation(src, annotation.PendingApproval)
+			if err := r.client.Patch(ctx, src, patch); err != nil {
+				return 0, err
+			}
+			r.announce(ctx, src, string(kind), corev1.EventTypeNormal, "ChangeApproved",
+				fmt.Sprintf("%s was approved by %s, rolling it out", change, decision.Approver), details)
+		}
+		return 0, nil
+	}
+
+	switch {
+	case !hasPending:
+		deadline := now.Add(r.store.Get().Approval.Timeout.Duration)
+		pending = approval.Pending{Hash: hash, Since: now.UTC().Truncate(time.Second), Deadline: deadline.UTC().Truncate(time.Second),
+			Manager: attribution.Manager}
+		if err := approval.Set(src, annotation.PendingApproval, pending); err != nil {
+			return 0, err
+		}
+		if err := r.client.Patch(ctx, src, patch); err != nil {
+			return 0, err
+		}
+		r.announce(ctx, src, string(kind), corev1.EventTypeNormal, "ApprovalRequired", fmt.Sprintf(
+			"%s waits for approval until %s, approve it with: reloader-server approve %s/%s -n %s",
+			change, pending.Deadline.Format(time.RFC3339), strings.ToLower(string(kind)), src.GetName(), src.GetNamespace()),
+			changeDetails(hash, attribution, "deadline", pending.Deadline.Format(time.RFC3339)))
+		return deadline.Sub(now), nil
+	case pending.Outcome != "":
+		return 0, nil
+	case !now.Before(pending.Deadline):
+		pending.Outcome = approval.Expired
+		if err := approval.Set(src, annotation.PendingApproval, pending); err != nil {
+			return 0, err
+		}
+		if err := r.client.Patch(ctx, src, patch); err != nil {
+			return 0, err
+		}
+		r.announce(ctx, src, string(kind), corev1.EventTypeWarning, "ApprovalExpired",
+			fmt.Sprintf("%s was not approved before %s and will not be rolled out", change, pending.Deadline.Format(time.RFC3339)),
+			changeDetails(hash, attribution))
+		return 0, nil
+	}
+	return pending.Deadline.Sub(now), nil
+}
+
+// unapprovedSource returns the critical source consumed by w whose content
+// differs from the content last approved.
+func (r *reloader) unapprovedSource(ctx context.Context, w workload.Workload) (string, bool, error) {
+	namespace := w.Object().GetNamespace()
+	for _, ref := range workload.References(w) {
+		src := workload.NewSource(ref.Kind)
+		if err := r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, src); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return "", false, err
+		}
+		if !approval.Critical(src) {
+			continue
+		}
+		hashes, err := workload.SourceHashes(ctx, r.client, workload.Reference{Kind: ref.Kind, Name: ref.Name}, namespace)
+		if err != nil {
+			return "", false, err
+		}
+		if workload.ContentHash(hashes) != src.GetAnnotations()[annotation.ApprovedHash] {
+			return ref.String(), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// changeDetails returns the notification details of the change to hash,
+// with who made it and the given key value pairs.
+func changeDetails(hash string, attribution workload.Attribution, kv ...string) map[string]string {
+	details := map[string]string{"hash": hash}
+	if attribution.Manager != "" {
+		details["changedBy"] = attribution.Manager
+	}
+	if attribution.Operation != "" {
+		details["operation"] = attribution.Operation
+	}
+	if !attribution.Time.IsZero() {
+		details["changedAt"] = attribution.Time.UTC().Format(time.RFC3339)
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		details[kv[i]] = kv[i+1]
+	}
+	return details
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
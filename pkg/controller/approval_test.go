@@ -0,0 +1,226 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/approval"
+	"github.com/hotkimho/reloader-server/project/pkg/notify"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+var _ = Describe("Change approval", func() {
+	var (
+		ctx      context.Context
+		c        client.Client
+		r        *reloader
+		recorder *record.FakeRecorder
+		server   *httptest.Server
+		received chan notify.Notification
+		key      = types.NamespacedName{Namespace: "default", Name: "app-config"}
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name: "web", Namespace: "default", Annotations: map[string]string{annotation.Auto: "true"},
+		}}
+		d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "web", EnvFrom: []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}}},
+		}}}
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default", Labels: map[string]string{annotation.Critical: "true"}},
+			Data:       map[string]string{"level": "info"},
+		}
+		approved := workload.ContentHash(workload.KeyHashes(map[string][]byte{"level": []byte("info")}))
+		cm.Annotations = map[string]string{annotation.ApprovedHash: approved}
+		c = newFakeClient(d, cm, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+
+		received = make(chan notify.Notification, 10)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			var n notify.Notification
+			Expect(json.NewDecoder(req.Body).Decode(&n)).To(Succeed())
+			received <- n
+		}))
+		DeferCleanup(server.Close)
+
+		cfg := config.NewConfig()
+		cfg.Notifications.WebhookURL = server.URL
+		cfg.Reload.Cooldown = metav1.Duration{}
+		recorder = record.NewFakeRecorder(20)
+		r = &reloader{client: c, recorder: recorder, store: config.NewStore(cfg)}
+
+		_, err := r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(getSource(ctx, c, key).Annotations).NotTo(HaveKey(annotation.PendingApproval))
+	})
+
+	getHash := func() string {
+		d := &appsv1.Deployment{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web"}, d)).To(Succeed())
+		return d.Annotations[annotation.ConfigHash]
+	}
+	change := func() string {
+		cm := getSource(ctx, c, key)
+		cm.Data["level"] = "debug"
+		// admission webhook 이 기록하는 작성자
+		cm.Annotations[annotation.ChangedBy] = "carol"
+		Expect(c.Update(ctx, cm)).To(Succeed())
+
+		before := getHash()
+		result, err := r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically("~", 24*time.Hour, time.Minute))
+		Expect(getHash()).To(Equal(before))
+		d := &appsv1.Deployment{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web"}, d)).To(Succeed())
+		queued, _ := getPendingReload(d)
+		Expect(queued.Reason).To(Equal(reasonSourceApproval("ConfigMap/app-config")))
+
+		pending, ok := approval.GetPending(getSource(ctx, c, key))
+		Expect(ok).To(BeTrue())
+		Expect(pending.Outcome).To(BeEmpty())
+		Eventually(received).Should(Receive(HaveField("Reason", "ApprovalRequired")))
+		return pending.Hash
+	}
+	decide := func(d approval.Decision) {
+		cm := getSource(ctx, c, key)
+		Expect(approval.Set(cm, annotation.Approval, d)).To(Succeed())
+		Expect(c.Update(ctx, cm)).To(Succeed())
+		_, err := r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	It("rolls out approved changes", func() {
+		before := getHash()
+		hash := change()
+
+		decide(approval.Decision{Hash: hash, Approver: "alice"})
+		Expect(getHash()).NotTo(Equal(before))
+		cm := getSource(ctx, c, key)
+		Expect(cm.Annotations).To(HaveKeyWithValue(annotation.ApprovedHash, hash))
+		Expect(cm.Annotations).NotTo(HaveKey(annotation.PendingApproval))
+		Eventually(received).Should(Receive(And(
+			HaveField("Reason", "ChangeApproved"),
+			HaveField("Details", HaveKeyWithValue("approver", "alice")),
+		)))
+	})
+
+	It("ignores approvals by the author of the change", func() {
+		before := getHash()
+		hash := change()
+
+		decide(approval.Decision{Hash: hash, Approver: "carol"})
+		Expect(getHash()).To(Equal(before))
+		cm := getSource(ctx, c, key)
+		Expect(cm.Annotations[annotation.ApprovedHash]).NotTo(Equal(hash))
+		Expect(cm.Annotations).NotTo(HaveKey(annotation.Approval))
+		Eventually(recorder.Events).Should(Receive(ContainSubstring("ApprovalIgnored")))
+	})
+
+	It("requires approval of the first content of critical sources", func() {
+		cm := getSource(ctx, c, key)
+		delete(cm.Annotations, annotation.ApprovedHash)
+		Expect(c.Update(ctx, cm)).To(Succeed())
+
+		_, err := r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		pending, ok := approval.GetPending(getSource(ctx, c, key))
+		Expect(ok).To(BeTrue())
+		Expect(pending.Hash).To(Equal(workload.ContentHash(workload.KeyHashes(map[string][]byte{"level": []byte("info")}))))
+		Eventually(received).Should(Receive(HaveField("Reason", "ApprovalRequired")))
+	})
+
+	It("credits the change to the manager of the changed keys", func() {
+		cm := getSource(ctx, c, key)
+		cm.Data["level"] = "debug"
+		cm.Data["region"] = "eu"
+		now := metav1.Now()
+		cm.ManagedFields = []metav1.ManagedFieldsEntry{
+			{Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate, Time: &metav1.Time{Time: now.Add(-time.Hour)},
+				FieldsType: "FieldsV1", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:level":{}}}`)}},
+			{Manager: "helm", Operation: metav1.ManagedFieldsOperationUpdate, Time: &now,
+				FieldsType: "FieldsV1", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:region":{}}}`)}},
+		}
+		Expect(c.Update(ctx, cm)).To(Succeed())
+		// 이전 내용에도 region 이 있었던 것처럼 기록
+		r.observed.Store(sourceID("ConfigMap", key), observedSource{
+			hash: "old", keys: workload.KeyHashes(map[string][]byte{"level": []byte("info"), "region": []byte("eu")}),
+		})
+		_, err := r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(received).Should(Receive(And(
+			HaveField("Reason", "ApprovalRequired"),
+			HaveField("Details", HaveKeyWithValue("changedBy", "kubectl-edit")),
+		)))
+		pending, _ := approval.GetPending(getSource(ctx, c, key))
+		Expect(pending.Manager).To(Equal("kubectl-edit"))
+
+		Expect(r.managerLabel("kubectl-edit")).To(Equal("kubectl-edit"))
+		Expect(r.managerLabel("deploy-script-7f9c")).To(Equal("other"))
+	})
+
+	It("holds back rejected changes", func() {
+		before := getHash()
+		hash := change()
+
+		decide(approval.Decision{Hash: hash, Approver: "bob", Rejected: true})
+		Expect(getHash()).To(Equal(before))
+		pending, _ := approval.GetPending(getSource(ctx, c, key))
+		Expect(pending.Outcome).To(Equal(approval.Rejected))
+		Eventually(recorder.Events).Should(Receive(ContainSubstring("was rejected by bob")))
+		Eventually(received).Should(Receive(HaveField("Reason", "ChangeRejected")))
+	})
+
+	It("expires changes not approved in time", func() {
+		hash := change()
+
+		cm := getSource(ctx, c, key)
+		_, err := r.reviewChange(ctx, "ConfigMap", cm, hash, workload.Attribution{}, time.Now().Add(25*time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		pending, _ := approval.GetPending(getSource(ctx, c, key))
+		Expect(pending.Outcome).To(Equal(approval.Expired))
+		Eventually(received).Should(Receive(HaveField("Reason", "ApprovalExpired")))
+
+		decide(approval.Decision{Hash: hash, Approver: "alice"})
+		Expect(getSource(ctx, c, key).Annotations[annotation.ApprovedHash]).NotTo(Equal(hash))
+		Eventually(recorder.Events).Should(Receive(ContainSubstring("ApprovalIgnored")))
+	})
+})
+
+func getSource(ctx context.Context, c client.Client, key types.NamespacedName) *corev1.ConfigMap {
+	cm := &corev1.ConfigMap{}
+	Expect(c.Get(ctx, key, cm)).To(Succeed())
+	return cm
+}
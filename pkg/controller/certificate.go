@@ -0,0 +1,95 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// observeCertificates exports the expiry of the certificate in the given TLS
+// Secret and of the earliest expiring certificate of every workload consuming it.
+func (r *reloader) observeCertificates(ctx context.Context, key types.NamespacedName) error {
+	if expiry, ok, err := r.certificateExpiry(ctx, key); err != nil {
+		return err
+	} else if ok {
+		certificateExpiry.WithLabelValues(key.Namespace, key.Name).Set(float64(expiry.Unix()))
+	} else {
+		certificateExpiry.DeleteLabelValues(key.Namespace, key.Name)
+	}
+
+	ref := workload.Reference{Kind: workload.Secret, Name: key.Name}
+	workloads, err := workload.List(ctx, r.client, key.Namespace, client.MatchingFields{workload.SourceIndex: ref.String()})
+	if err != nil {
+		return err
+	}
+	for _, w := range workloads {
+		if err := r.observeWorkloadCertificates(ctx, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// observeWorkloadCertificates exports the expiry of the earliest expiring
+// certificate consumed by w, or drops the series when w consumes none.
+func (r *reloader) observeWorkloadCertificates(ctx context.Context, w workload.Workload) error {
+	obj := w.Object()
+	var earliest time.Time
+	for _, ref := range workload.References(w) {
+		if ref.Kind != workload.Secret {
+			continue
+		}
+		expiry, ok, err := r.certificateExpiry(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: ref.Name})
+		if err != nil {
+			return err
+		}
+		if ok && (earliest.IsZero() || expiry.Before(earliest)) {
+			earliest = expiry
+		}
+	}
+	if earliest.IsZero() {
+		workloadCertificateExpiry.DeleteLabelValues(obj.GetNamespace(), w.Kind(), obj.GetName())
+		return nil
+	}
+	workloadCertificateExpiry.WithLabelValues(obj.GetNamespace(), w.Kind(), obj.GetName()).Set(float64(earliest.Unix()))
+	return nil
+}
+
+// certificateExpiry returns the expiry of the certificate of a TLS Secret, or
+// false if the Secret does not exist, is not a TLS Secret or holds no certificate.
+func (r *reloader) certificateExpiry(ctx context.Context, key types.NamespacedName) (time.Time, bool, error) {
+	secret := &corev1.Secret{}
+	if err := r.client.Get(ctx, key, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+	if secret.Type != corev1.SecretTypeTLS {
+		return time.Time{}, false, nil
+	}
+	expiry, ok := workload.CertificateExpiry(secret.Data)
+	return expiry, ok, nil
+}
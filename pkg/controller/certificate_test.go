@@ -0,0 +1,100 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+)
+
+var _ = Describe("Certificate expiry", func() {
+	var (
+		ctx    context.Context
+		r      *reloader
+		d      *appsv1.Deployment
+		secret *corev1.Secret
+		key    = types.NamespacedName{Namespace: "default", Name: "tls"}
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		certificateExpiry.Reset()
+		workloadCertificateExpiry.Reset()
+		pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "example.com"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(24 * time.Hour),
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, pk.Public(), pk)
+		Expect(err).NotTo(HaveOccurred())
+
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "tls", Namespace: "default"},
+			Type:       corev1.SecretTypeTLS,
+			Data:       map[string][]byte{corev1.TLSCertKey: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})},
+		}
+		d = &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		d.Spec.Template.Spec.Volumes = []corev1.Volume{{Name: "tls", VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: "tls"},
+		}}}
+		r = &reloader{client: newFakeClient(d, secret), store: config.NewStore(config.NewConfig())}
+		Expect(r.observeCertificates(ctx, key)).To(Succeed())
+		Expect(testutil.CollectAndCount(certificateExpiry)).To(Equal(1))
+		Expect(testutil.CollectAndCount(workloadCertificateExpiry)).To(Equal(1))
+	})
+
+	It("drops the series of a deleted Secret", func() {
+		Expect(r.client.Delete(ctx, secret)).To(Succeed())
+		Expect(r.observeCertificates(ctx, key)).To(Succeed())
+		Expect(testutil.CollectAndCount(certificateExpiry)).To(BeZero())
+		Expect(testutil.CollectAndCount(workloadCertificateExpiry)).To(BeZero())
+	})
+
+	It("drops the series of a workload that no longer references the Secret", func() {
+		updated := d.DeepCopy()
+		updated.Spec.Template.Spec.Volumes = nil
+		r.workloadMetrics().Update(ctx, event.UpdateEvent{ObjectOld: d, ObjectNew: updated}, nil)
+		Expect(testutil.CollectAndCount(workloadCertificateExpiry)).To(BeZero())
+	})
+
+	It("drops the series of a deleted workload", func() {
+		r.workloadMetrics().Delete(ctx, event.DeleteEvent{Object: d}, nil)
+		Expect(testutil.CollectAndCount(workloadCertificateExpiry)).To(BeZero())
+	})
+})
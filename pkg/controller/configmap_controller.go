@@ -18,40 +18,256 @@ package controller
 
 import (
 	"context"
-	"fmt"
+	"strings"
+
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	reloaderv1alpha1 "github.com/hotkimho/reloader-server/project/api/v1alpha1"
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
 )
 
-// ConfigMapReconciler reconciles a ConfigMap object
+// ConfigMapReconciler reloads the workloads consuming a ConfigMap
 type ConfigMapReconciler struct {
-	client client.Client
-	scheme *runtime.Scheme
+	*reloader
+}
+
+// SecretReconciler reloads the workloads consuming a Secret
+type SecretReconciler struct {
+	*reloader
 }
 
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=configmaps/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=configmaps/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=argoproj.io,resources=rollouts,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=apps.openshift.io,resources=deploymentconfigs,verbs=get;list;watch;update;patch
 
 func (r *ConfigMapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
-	// TODO(user): your logic here
+	// 컨트롤러 설정 hot reload
+	if req.NamespacedName == r.store.Key {
+		return r.reconcileConfig(ctx)
+	}
+	return r.reconcileSource(ctx, workload.ConfigMap, req.NamespacedName)
+}
 
-	fmt.Println("test : ", req.NamespacedName)
-	return ctrl.Result{}, nil
+func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	result, err := r.reconcileSource(ctx, workload.Secret, req.NamespacedName)
+	if err != nil {
+		return result, err
+	}
+	if err := r.observeCertificates(ctx, req.NamespacedName); err != nil {
+		log.FromContext(ctx).Error(err, "unable to observe certificate expiry")
+		return ctrl.Result{}, err
+	}
+	return result, nil
 }
 
-func SetupWithManager(mgr ctrl.Manager) error {
+func SetupWithManager(mgr ctrl.Manager, store *config.Store) error {
+	r, err := newReloader(mgr, store)
+	if err != nil {
+		return err
+	}
+	if err := registerWorkloads(mgr, store.Get().Workloads); err != nil {
+		return err
+	}
+
+	// source 별로 참조하는 워크로드를 찾기 위한 index
+	indexer := mgr.GetFieldIndexer()
+	for _, obj := range workload.Objects() {
+		if err := indexer.IndexField(context.Background(), obj, workload.SourceIndex, workload.IndexSources); err != nil {
+			return err
+		}
+		// registry credential 은 pod spec 이나 ServiceAccount 로 참조
+		if err := indexer.IndexField(context.Background(), obj, workload.PullSecretIndex, workload.IndexPullSecrets); err != nil {
+			return err
+		}
+		if err := indexer.IndexField(context.Background(), obj, workload.ServiceAccountIndex, workload.IndexServiceAccount); err != nil {
+			return err
+		}
+	}
+	if err := indexer.IndexField(context.Background(), &corev1.ServiceAccount{}, workload.PullSecretIndex, workload.IndexPullSecrets); err != nil {
+		return err
+	}
+
+	cmBuilder := ctrl.NewControllerManagedBy(mgr).
+		Named("configmap").
+		For(&corev1.ConfigMap{})
+	secretBuilder := ctrl.NewControllerManagedBy(mgr).
+		Named("secret").
+		For(&corev1.Secret{})
+
+	// 워크로드의 annotation 이 바뀌면 (e.g. hold 해제) 참조하는 source 를 다시 reconcile
+	workloadPredicates := builder.WithPredicates(predicate.Or(
+		predicate.GenerationChangedPredicate{},
+		predicate.AnnotationChangedPredicate{},
+	))
+	for _, obj := range workload.Objects() {
+		cmBuilder = cmBuilder.Watches(obj, handler.EnqueueRequestsFromMapFunc(r.referencedSources(workload.ConfigMap)), workloadPredicates)
+		secretBuilder = secretBuilder.Watches(obj, handler.EnqueueRequestsFromMapFunc(r.referencedSources(workload.Secret)), workloadPredicates)
+		// 삭제되거나 hold 가 해제된 워크로드, 더 이상 참조하지 않는 TLS Secret 의 metric 정리
+		secretBuilder = secretBuilder.Watches(obj, r.workloadMetrics())
+	}
+	// policy 가 생기거나 바뀌면 선택된 source 를 다시 reconcile
+	cmBuilder = cmBuilder.Watches(&reloaderv1alpha1.ReloadPolicy{}, handler.EnqueueRequestsFromMapFunc(r.policySources(workload.ConfigMap)),
+		builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	secretBuilder = secretBuilder.Watches(&reloaderv1alpha1.ReloadPolicy{}, handler.EnqueueRequestsFromMapFunc(r.policySources(workload.Secret)),
+		builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	cmBuilder = cmBuilder.Watches(&reloaderv1alpha1.ClusterReloadPolicy{}, handler.EnqueueRequestsFromMapFunc(r.clusterPolicySources(workload.ConfigMap)),
+		builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	secretBuilder = secretBuilder.Watches(&reloaderv1alpha1.ClusterReloadPolicy{}, handler.EnqueueRequestsFromMapFunc(r.clusterPolicySources(workload.Secret)),
+		builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+
+	if err := cmBuilder.Complete(&ConfigMapReconciler{r}); err != nil {
+		return err
+	}
+	if err := secretBuilder.Complete(&SecretReconciler{r}); err != nil {
+		return err
+	}
+
+	// restart-schedule annotation 이 있는 워크로드만 주기적으로 reload
+	scheduled := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		_, ok := obj.GetAnnotations()[annotation.RestartSchedule]
+		_, state := obj.GetAnnotations()[annotation.ScheduledRestart]
+		return ok || state
+	})
+	for _, obj := range workload.Objects() {
+		w, _ := workload.New(obj)
+		if w == nil || w.Kind() == workload.CronJob {
+			continue
+		}
+		newObject := objectFactory(obj)
+		if err := ctrl.NewControllerManagedBy(mgr).
+			Named("schedule-"+strings.ToLower(w.Kind())).
+			For(obj, builder.WithPredicates(scheduled)).
+			Complete(&ScheduleReconciler{reloader: r, object: newObject}); err != nil {
+			return err
+		}
+	}
+
+	// policy 가 선택한 namespace, 워크로드를 status 에 기록
+	targetPredicates := builder.WithPredicates(predicate.Or(
+		predicate.GenerationChangedPredicate{},
+		predicate.LabelChangedPredicate{},
+	))
+	policyBuilder := ctrl.NewControllerManagedBy(mgr).
+		Named("reloadpolicy").
+		For(&reloaderv1alpha1.ReloadPolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	clusterPolicyBuilder := ctrl.NewControllerManagedBy(mgr).
+		Named("clusterreloadpolicy").
+		For(&reloaderv1alpha1.ClusterReloadPolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.Namespace{}, enqueueMatching(r.clusterPolicies),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}))
+	for _, obj := range workload.Objects() {
+		policyBuilder = policyBuilder.Watches(obj, enqueueMatching(r.namespacePolicies), targetPredicates)
+		clusterPolicyBuilder = clusterPolicyBuilder.Watches(obj, enqueueMatching(r.clusterPolicies), targetPredicates)
+	}
+	// source label 이 바뀌면 sources selector 로 선택하는 policy 를 다시 계산
+	for _, obj := range []client.Object{&corev1.ConfigMap{}, &corev1.Secret{}} {
+		policyBuilder = policyBuilder.Watches(obj, enqueueMatching(r.namespacePolicies),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}))
+		clusterPolicyBuilder = clusterPolicyBuilder.Watches(obj, enqueueMatching(r.clusterPolicies),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}))
+	}
+	if err := policyBuilder.Complete(&PolicyStatusReconciler{r}); err != nil {
+		return err
+	}
+	if err := clusterPolicyBuilder.Complete(&ClusterPolicyStatusReconciler{r}); err != nil {
+		return err
+	}
+
+	// reload 기록의 rollout 진행 상황 추적, 보관 기간이 지나면 삭제
+	recordBuilder := ctrl.NewControllerManagedBy(mgr).
+		Named("reloadrecord").
+		For(&reloaderv1alpha1.ReloadRecord{}, builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	for _, obj := range workload.Objects() {
+		recordBuilder = recordBuilder.Watches(obj, handler.EnqueueRequestsFromMapFunc(r.workloadRecords))
+	}
+	if err := recordBuilder.Complete(&ReloadRecordReconciler{r}); err != nil {
+		return err
+	}
+
+	// pause 해제, maintenance window 변경 시 pending reload 재평가
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.ConfigMap{}).
-		// secret 왓치 추가
-		Watches(&corev1.Secret{}, &handler.EnqueueRequestForObject{}).
-		Complete(&ConfigMapReconciler{
-			client: mgr.GetClient(),
-			scheme: mgr.GetScheme(),
-		})
+		Named("namespace").
+		For(&corev1.Namespace{}, builder.WithPredicates(predicate.AnnotationChangedPredicate{})).
+		Complete(&NamespaceReconciler{r})
+}
+
+// registerWorkloads registers the configured generic workload kinds. Kinds
+// unknown to the API server are skipped so a missing CRD does not stop the manager.
+func registerWorkloads(mgr ctrl.Manager, cfgs []config.WorkloadConfig) error {
+	logger := mgr.GetLogger().WithName("workload")
+	for _, cfg := range cfgs {
+		gvk := schema.GroupVersionKind{Group: cfg.Group, Version: cfg.Version, Kind: cfg.Kind}
+		if _, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+			if meta.IsNoMatchError(err) {
+				logger.Info("workload kind is not served, skipping", "gvk", gvk)
+				continue
+			}
+			return err
+		}
+		if err := workload.Register(workload.Generic{
+			GVK:          gvk,
+			TemplatePath: cfg.TemplatePath,
+			SelectorPath: cfg.SelectorPath,
+		}); err != nil {
+			return err
+		}
+		logger.Info("registered workload kind", "gvk", gvk)
+	}
+	return nil
+}
+
+// objectFactory returns a function creating empty objects of the kind of obj.
+func objectFactory(obj client.Object) func() client.Object {
+	return func() client.Object {
+		empty := obj.DeepCopyObject().(client.Object)
+		if u, ok := empty.(*unstructured.Unstructured); ok {
+			gvk := u.GroupVersionKind()
+			u.Object = map[string]interface{}{}
+			u.SetGroupVersionKind(gvk)
+		}
+		return empty
+	}
+}
+
+// referencedSources maps a workload to the sources of the given kind it consumes.
+func (r *reloader) referencedSources(kind workload.SourceKind) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		w, ok := workload.New(obj)
+		if !ok {
+			return nil
+		}
+		if governed, err := r.governs(ctx, w, kind, nil); err != nil || !governed {
+			return nil
+		}
+
+		var reqs []reconcile.Request
+		for _, ref := range workload.References(w) {
+			if ref.Kind != kind {
+				continue
+			}
+			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKey{
+				Namespace: obj.GetNamespace(),
+				Name:      ref.Name,
+			}})
+		}
+		return reqs
+	}
 }
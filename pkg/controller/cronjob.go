@@ -0,0 +1,178 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/strategy"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+
+// scheduledTimestamp is the annotation the CronJob controller sets on the Jobs it creates.
+const scheduledTimestamp = "batch.kubernetes.io/cronjob-scheduled-timestamp"
+
+// updateJobTemplate deals with the Jobs already created from the job
+// template of a CronJob, then stamps hash on the template so that future
+// Jobs pick up the change. The Jobs come first so that a failure is retried
+// by the next reconcile, which skips the Jobs already recreated.
+func (r *reloader) updateJobTemplate(ctx context.Context, w workload.Workload, hash, cause string, now time.Time) error {
+	if err := r.reconcileJobs(ctx, w, hash); err != nil {
+		return err
+	}
+	return r.commit(ctx, w, hash, strategy.Restart, now, func() {
+		w.SetTemplateAnnotation(annotation.ConfigHash, hash)
+	}, "Updated the job template to pick up configuration changes: "+cause)
+}
+
+// reconcileJobs recreates the outdated Jobs of a CronJob whose pods have not
+// started yet, if asked to, and warns about the outdated Jobs still running.
+func (r *reloader) reconcileJobs(ctx context.Context, w workload.Workload, hash string) error {
+	cronJob, ok := w.Object().(*batchv1.CronJob)
+	if !ok {
+		return nil
+	}
+	recreate := cronJob.Annotations[annotation.RecreatePendingJobs] == "true"
+
+	jobs := &batchv1.JobList{}
+	if err := r.client.List(ctx, jobs, client.InNamespace(cronJob.Namespace)); err != nil {
+		return err
+	}
+
+	var running, recreated []string
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if !metav1.IsControlledBy(job, cronJob) || jobFinished(job) || job.Spec.Template.Annotations[annotation.ConfigHash] == hash {
+			continue
+		}
+		started, err := r.jobStarted(ctx, job)
+		if err != nil {
+			return err
+		}
+		if started || !recreate {
+			running = append(running, job.Name)
+			continue
+		}
+
+		name, err := r.recreateJob(ctx, cronJob, job, hash)
+		if err != nil {
+			return err
+		}
+		recreated = append(recreated, job.Name)
+		log.FromContext(ctx).Info("recreated pending job", "cronjob", cronJob.Name, "job", job.Name, "replacement", name)
+	}
+
+	if len(recreated) > 0 {
+		r.recorder.Eventf(cronJob, corev1.EventTypeNormal, "JobsRecreated",
+			"Recreated %d pending Jobs (%s) from the updated job template", len(recreated), strings.Join(recreated, ", "))
+	}
+	if len(running) > 0 {
+		r.recorder.Eventf(cronJob, corev1.EventTypeWarning, "OutdatedJobs",
+			"%d Jobs (%s) still run with the previous configuration", len(running), strings.Join(running, ", "))
+	}
+	return nil
+}
+
+// jobStarted reports whether any pod of job got past the Pending phase.
+func (r *reloader) jobStarted(ctx context.Context, job *batchv1.Job) (bool, error) {
+	if job.Status.Succeeded > 0 || job.Status.Failed > 0 {
+		return true, nil
+	}
+	if job.Status.Active == 0 || job.Spec.Selector == nil {
+		return false, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return false, err
+	}
+	pods := &corev1.PodList{}
+	if err := r.client.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return false, err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodPending {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// recreateJob replaces job with a Job created from the job template of
+// cronJob and hash, keeping its owner and schedule, and returns the name of
+// the new Job. The replacement is created before job is deleted, under a
+// name derived from hash, so that a failure at any step loses no run and a
+// retry does not create a second replacement.
+func (r *reloader) recreateJob(ctx context.Context, cronJob *batchv1.CronJob, job *batchv1.Job, hash string) (string, error) {
+	template := cronJob.Spec.JobTemplate.DeepCopy()
+	replacement := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            truncate(job.Name, 50) + "-" + shortHash(hash),
+			Namespace:       job.Namespace,
+			Labels:          template.Labels,
+			Annotations:     template.Annotations,
+			OwnerReferences: job.OwnerReferences,
+		},
+		Spec: template.Spec,
+	}
+	if ts, ok := job.Annotations[scheduledTimestamp]; ok {
+		if replacement.Annotations == nil {
+			replacement.Annotations = map[string]string{}
+		}
+		replacement.Annotations[scheduledTimestamp] = ts
+	}
+	if replacement.Spec.Template.Annotations == nil {
+		replacement.Spec.Template.Annotations = map[string]string{}
+	}
+	replacement.Spec.Template.Annotations[annotation.ConfigHash] = hash
+
+	if err := r.client.Create(ctx, replacement); err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", err
+	}
+	if err := r.client.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+		return "", err
+	}
+	return replacement.Name, nil
+}
+
+func jobFinished(job *batchv1.Job) bool {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+var _ = Describe("CronJob reloads", func() {
+	var (
+		ctx      context.Context
+		cronJob  *batchv1.CronJob
+		recorder *record.FakeRecorder
+	)
+
+	newJob := func(name string, active int32) *batchv1.Job {
+		job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+		job.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"job-name": name}}
+		job.Spec.Template.Annotations = map[string]string{annotation.ConfigHash: "old"}
+		job.Status.Active = active
+		job.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(cronJob, batchv1.SchemeGroupVersion.WithKind("CronJob"))}
+		return job
+	}
+	newPod := func(job string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: job + "-pod", Namespace: "default", Labels: map[string]string{"job-name": job}},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	newReloader := func(objs ...client.Object) *reloader {
+		c := newFakeClient(append(objs, cronJob)...)
+		return &reloader{client: c, recorder: recorder, store: config.NewStore(config.NewConfig())}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		recorder = record.NewFakeRecorder(10)
+		cronJob = &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{
+			Name: "report", Namespace: "default", UID: "cronjob-uid",
+			Annotations: map[string]string{annotation.RecreatePendingJobs: "true"},
+		}}
+	})
+
+	It("recreates pending jobs and warns about running ones", func() {
+		r := newReloader(
+			newJob("pending", 1), newPod("pending", corev1.PodPending),
+			newJob("running", 1), newPod("running", corev1.PodRunning),
+		)
+		w, _ := workload.New(cronJob)
+		w.SetTemplateAnnotation(annotation.ConfigHash, "new")
+
+		Expect(r.reconcileJobs(ctx, w, "new")).To(Succeed())
+
+		jobs := &batchv1.JobList{}
+		Expect(r.client.List(ctx, jobs)).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(2))
+		Expect(jobs.Items).To(ContainElement(HaveField("ObjectMeta.Name", "running")))
+		Expect(jobs.Items).NotTo(ContainElement(HaveField("ObjectMeta.Name", "pending")))
+		Expect(recorder.Events).To(Receive(ContainSubstring("JobsRecreated")))
+		Expect(recorder.Events).To(Receive(ContainSubstring("running")))
+	})
+
+	It("keeps pending jobs until their replacement is created", func() {
+		r := newReloader(newJob("pending", 1), newPod("pending", corev1.PodPending))
+		w, _ := workload.New(cronJob)
+		c := r.client
+		r.client = failingCreate{c}
+
+		Expect(r.reconcileJobs(ctx, w, "new")).NotTo(Succeed())
+		Expect(r.client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "pending"}, &batchv1.Job{})).To(Succeed())
+
+		r.client = c
+		Expect(r.reconcileJobs(ctx, w, "new")).To(Succeed())
+		Expect(r.reconcileJobs(ctx, w, "new")).To(Succeed())
+		jobs := &batchv1.JobList{}
+		Expect(r.client.List(ctx, jobs)).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(1))
+		Expect(jobs.Items[0].Spec.Template.Annotations).To(HaveKeyWithValue(annotation.ConfigHash, "new"))
+	})
+
+	It("only warns when recreation is not requested", func() {
+		delete(cronJob.Annotations, annotation.RecreatePendingJobs)
+		r := newReloader(newJob("pending", 0))
+		w, _ := workload.New(cronJob)
+
+		Expect(r.reconcileJobs(ctx, w, "new")).To(Succeed())
+
+		Expect(r.client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "pending"}, &batchv1.Job{})).To(Succeed())
+		Expect(recorder.Events).To(Receive(ContainSubstring("OutdatedJobs")))
+	})
+})
+
+// failingCreate rejects every create, e.g. when a quota is exhausted.
+type failingCreate struct {
+	client.Client
+}
+
+func (f failingCreate) Create(context.Context, client.Object, ...client.CreateOption) error {
+	return apierrors.NewForbidden(batchv1.Resource("jobs"), "", errors.New("exceeded quota"))
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+)
+
+var _ = Describe("Deleted sources", func() {
+	var (
+		ctx context.Context
+		r   *reloader
+		d   *appsv1.Deployment
+		cfg *config.Config
+		key = types.NamespacedName{Namespace: "default", Name: "web"}
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		d = &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name: "web", Namespace: "default", Annotations: map[string]string{annotation.Auto: "true"},
+		}}
+		d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "web", EnvFrom: []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web"}, Optional: ptr.To(true)}},
+		}}}
+		cfg = config.NewConfig()
+		cfg.Reload.Cooldown = metav1.Duration{}
+	})
+
+	// deleteSource 는 workload 가 현재 내용으로 reload 된 뒤 source 를 삭제하고 restart 여부를 반환
+	deleteSource := func() bool {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Data: map[string]string{"A": "1"}}
+		c := newFakeClient(d, cm, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+		r = &reloader{client: c, recorder: record.NewFakeRecorder(10), store: config.NewStore(cfg)}
+		_, err := r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+
+		before := &appsv1.Deployment{}
+		Expect(r.client.Get(ctx, key, before)).To(Succeed())
+		Expect(r.client.Delete(ctx, cm)).To(Succeed())
+		_, err = r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+
+		after := &appsv1.Deployment{}
+		Expect(r.client.Get(ctx, key, after)).To(Succeed())
+		return after.Spec.Template.Annotations[annotation.ConfigHash] != before.Spec.Template.Annotations[annotation.ConfigHash]
+	}
+
+	It("reloads on the deletion of optional sources", func() {
+		Expect(deleteSource()).To(BeTrue())
+	})
+
+	It("ignores the deletion of optional sources when the workload opts out", func() {
+		d.Annotations[annotation.IgnoreDelete] = "true"
+		Expect(deleteSource()).To(BeFalse())
+	})
+
+	It("ignores the deletion of optional sources when the config opts out", func() {
+		cfg.Reload.IgnoreDelete = true
+		Expect(deleteSource()).To(BeFalse())
+	})
+
+	It("never reloads on the deletion of required sources", func() {
+		d.Annotations[annotation.IgnoreDelete] = "false"
+		d.Spec.Template.Spec.Containers[0].EnvFrom[0].ConfigMapRef.Optional = nil
+		Expect(deleteSource()).To(BeFalse())
+	})
+})
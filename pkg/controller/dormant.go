@@ -0,0 +1,130 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// deferReload records hash on a paused or scaled to zero workload without
+// reloading it. The hash is stamped on the pod template, so the pods created
+// when the workload runs again pick up the change without a second rollout.
+func (r *reloader) deferReload(ctx context.Context, w workload.Workload, hash, cause, reason string) error {
+	obj := w.Object()
+	static, err := workload.StaticHash(ctx, r.client, w)
+	if err != nil {
+		return err
+	}
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+
+	w.SetTemplateAnnotation(annotation.ConfigHash, hash)
+	setAnnotation(obj, annotation.ConfigHash, hash)
+	setAnnotation(obj, annotation.StaticHash, static)
+	setAnnotation(obj, annotation.DeferredReload, hash)
+	removeAnnotation(obj, annotation.DeferredWaiting)
+	removeAnnotation(obj, annotation.PendingReload)
+	removeAnnotation(obj, annotation.Approve)
+	removeAnnotation(obj, annotation.SyncingReload)
+
+	if err := r.client.Patch(ctx, obj, patch); err != nil {
+		return err
+	}
+	r.recorder.Eventf(obj, corev1.EventTypeNormal, "ReloadDeferred",
+		"Recorded the configuration without restarting pods, %s: %s", reason, cause)
+	log.FromContext(ctx).Info("deferred reload of dormant workload", "workload", w.Kind()+"/"+obj.GetName(), "hash", hash, "reason", reason)
+	return nil
+}
+
+// confirmDeferred checks, once a workload with a deferred reload runs again,
+// that its pods come up with hash. The workload is only restarted when its
+// pod template lost the hash in the meantime. When no pod comes up ready
+// within the deferred timeout, the reload is no longer tracked.
+func (r *reloader) confirmDeferred(ctx context.Context, w workload.Workload, hash string, now time.Time) (time.Duration, error) {
+	obj := w.Object()
+	if _, dormant := workload.Dormant(w); dormant {
+		return 0, nil
+	}
+	if w.TemplateAnnotation(annotation.ConfigHash) != hash {
+		return 0, r.restart(ctx, w, hash, "the pod template lost the recorded configuration", now)
+	}
+
+	pods, err := workload.Pods(ctx, r.client, w)
+	if err != nil {
+		return 0, err
+	}
+	ready := 0
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		// 이전 설정의 pod 가 남아 있으면 rollout 이 끝날 때까지 대기
+		if pod.Annotations[annotation.ConfigHash] != hash {
+			return r.awaitDeferred(ctx, w, now)
+		}
+		if workload.Ready(pod) {
+			ready++
+		}
+	}
+	if ready == 0 {
+		return r.awaitDeferred(ctx, w, now)
+	}
+
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	removeAnnotation(obj, annotation.DeferredReload)
+	removeAnnotation(obj, annotation.DeferredWaiting)
+	if err := r.client.Patch(ctx, obj, patch); err != nil {
+		return 0, err
+	}
+	r.recorder.Eventf(obj, corev1.EventTypeNormal, "ReloadConfirmed", "%d ready pods run the latest configuration", ready)
+	return 0, nil
+}
+
+// awaitDeferred returns how long to wait before checking the pods of w
+// again, and gives up on them past the deferred timeout.
+func (r *reloader) awaitDeferred(ctx context.Context, w workload.Workload, now time.Time) (time.Duration, error) {
+	obj := w.Object()
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+
+	since, err := time.Parse(time.RFC3339, obj.GetAnnotations()[annotation.DeferredWaiting])
+	if err != nil {
+		setAnnotation(obj, annotation.DeferredWaiting, now.UTC().Format(time.RFC3339))
+		return retryInterval, r.client.Patch(ctx, obj, patch)
+	}
+	timeout := r.store.Get().Reload.DeferredTimeout.Duration
+	if now.Sub(since) < timeout {
+		return retryInterval, nil
+	}
+
+	removeAnnotation(obj, annotation.DeferredReload)
+	removeAnnotation(obj, annotation.DeferredWaiting)
+	if err := r.client.Patch(ctx, obj, patch); err != nil {
+		return 0, err
+	}
+	r.recorder.Eventf(obj, corev1.EventTypeWarning, "ReloadUnconfirmed",
+		"No ready pod runs the latest configuration %s after the workload ran again, no longer waiting", timeout)
+	log.FromContext(ctx).Info("gave up confirming deferred reload", "workload", w.Kind()+"/"+obj.GetName(), "since", since)
+	return 0, nil
+}
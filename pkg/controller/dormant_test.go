@@ -0,0 +1,124 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+var _ = Describe("Dormant workloads", func() {
+	var (
+		ctx    context.Context
+		c      client.Client
+		r      *reloader
+		d      *appsv1.Deployment
+		labels = map[string]string{"app": "web"}
+	)
+
+	newPod := func(name, hash string) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: name, Namespace: "default", Labels: labels,
+			Annotations: map[string]string{annotation.ConfigHash: hash},
+		}}
+		pod.Status.Phase = corev1.PodRunning
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		return pod
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		d = &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		d.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+		d.Spec.Paused = true
+	})
+
+	build := func(objs ...client.Object) {
+		c = newFakeClient(append(objs, d)...)
+		r = &reloader{client: c, recorder: record.NewFakeRecorder(10), store: config.NewStore(config.NewConfig())}
+	}
+
+	It("records the hash of a paused workload without restarting it", func() {
+		build()
+		w, _ := workload.New(d)
+		Expect(r.apply(ctx, w, nil, "new", "ConfigMap/web changed", time.Now())).To(BeZero())
+
+		Expect(d.Annotations).To(HaveKeyWithValue(annotation.ConfigHash, "new"))
+		Expect(d.Annotations).To(HaveKeyWithValue(annotation.DeferredReload, "new"))
+		Expect(d.Annotations).NotTo(HaveKey(annotation.ReloadHistory))
+		Expect(d.Spec.Template.Annotations).To(HaveKeyWithValue(annotation.ConfigHash, "new"))
+		Expect(d.Spec.Template.Annotations).NotTo(HaveKey(annotation.RestartedAt))
+	})
+
+	It("confirms the pods once the workload is resumed", func() {
+		build(newPod("old", "old"))
+		w, _ := workload.New(d)
+		Expect(r.apply(ctx, w, nil, "new", "ConfigMap/web changed", time.Now())).To(BeZero())
+
+		d.Spec.Paused = false
+		Expect(c.Update(ctx, d)).To(Succeed())
+		Expect(r.confirmDeferred(ctx, w, "new", time.Now())).To(Equal(retryInterval))
+
+		Expect(c.Delete(ctx, newPod("old", "old"))).To(Succeed())
+		Expect(c.Create(ctx, newPod("fresh", "new"))).To(Succeed())
+		Expect(r.confirmDeferred(ctx, w, "new", time.Now())).To(BeZero())
+		Expect(d.Annotations).NotTo(HaveKey(annotation.DeferredReload))
+		Expect(d.Spec.Template.Annotations).NotTo(HaveKey(annotation.RestartedAt))
+	})
+
+	It("stops waiting for pods that never become ready", func() {
+		build()
+		w, _ := workload.New(d)
+		Expect(r.apply(ctx, w, nil, "new", "ConfigMap/web changed", time.Now())).To(BeZero())
+
+		d.Spec.Paused = false
+		Expect(c.Update(ctx, d)).To(Succeed())
+		now := time.Now()
+		Expect(r.confirmDeferred(ctx, w, "new", now)).To(Equal(retryInterval))
+		Expect(d.Annotations).To(HaveKey(annotation.DeferredWaiting))
+		Expect(r.confirmDeferred(ctx, w, "new", now.Add(10*time.Minute))).To(Equal(retryInterval))
+
+		Expect(r.confirmDeferred(ctx, w, "new", now.Add(time.Hour))).To(BeZero())
+		Expect(d.Annotations).NotTo(HaveKey(annotation.DeferredReload))
+		Expect(d.Annotations).NotTo(HaveKey(annotation.DeferredWaiting))
+		Expect(r.recorder.(*record.FakeRecorder).Events).To(Receive(ContainSubstring("ReloadDeferred")))
+		Expect(r.recorder.(*record.FakeRecorder).Events).To(Receive(ContainSubstring("ReloadUnconfirmed")))
+	})
+
+	It("treats workloads scaled to zero as dormant", func() {
+		d.Spec.Paused = false
+		zero := int32(0)
+		d.Spec.Replicas = &zero
+		w, _ := workload.New(d)
+		reason, ok := workload.Dormant(w)
+		Expect(ok).To(BeTrue())
+		Expect(reason).To(Equal("scaled to zero"))
+	})
+})
@@ -0,0 +1,50 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	reloaderv1alpha1 "github.com/hotkimho/reloader-server/project/api/v1alpha1"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// fakeScheme holds the built-in and reloader types for the fake client.
+var fakeScheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(fakeScheme))
+	utilruntime.Must(reloaderv1alpha1.AddToScheme(fakeScheme))
+}
+
+// newFakeClient returns a fake client serving objs, with the workload indexes.
+func newFakeClient(objs ...client.Object) client.Client {
+	b := fake.NewClientBuilder().WithScheme(fakeScheme).WithObjects(objs...).
+		WithStatusSubresource(&reloaderv1alpha1.ReloadPolicy{}, &reloaderv1alpha1.ClusterReloadPolicy{}, &reloaderv1alpha1.ReloadRecord{})
+	for _, obj := range workload.Objects() {
+		b = b.WithIndex(obj, workload.SourceIndex, workload.IndexSources).
+			WithIndex(obj, workload.PullSecretIndex, workload.IndexPullSecrets).
+			WithIndex(obj, workload.ServiceAccountIndex, workload.IndexServiceAccount)
+	}
+	b = b.WithIndex(&corev1.ServiceAccount{}, workload.PullSecretIndex, workload.IndexPullSecrets)
+	return b.Build()
+}
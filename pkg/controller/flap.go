@@ -0,0 +1,120 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/policy"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// admission is the outcome of the cooldown and flap checks for a workload.
+type admission struct {
+	// held is true when the workload is already on hold.
+	held bool
+	// hold is the reason to put the workload on hold, if it is flapping.
+	hold string
+	// wait is the remaining cooldown.
+	wait time.Duration
+}
+
+// admit decides whether w, selected by the policies of s, may be reloaded at now.
+func (r *reloader) admit(w workload.Workload, s *policy.Settings, now time.Time) admission {
+	obj := w.Object()
+	annotations := s.Annotations(obj.GetAnnotations())
+
+	if _, ok := annotations[annotation.Hold]; ok {
+		workloadHeld.WithLabelValues(obj.GetNamespace(), w.Kind(), obj.GetName()).Set(1)
+		return admission{held: true}
+	}
+	workloadHeld.DeleteLabelValues(obj.GetNamespace(), w.Kind(), obj.GetName())
+
+	cfg := r.store.Get().Reload
+	history := reloadHistory(obj, now, cfg.FlapWindow.Duration)
+
+	cooldown := cfg.Cooldown.Duration
+	if v, ok := annotations[annotation.Cooldown]; ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			cooldown = d
+		}
+	}
+	if n := len(history); n > 0 {
+		if elapsed := now.Sub(history[n-1]); elapsed < cooldown {
+			return admission{wait: cooldown - elapsed}
+		}
+	}
+
+	if cfg.FlapThreshold > 0 && len(history) >= cfg.FlapThreshold {
+		return admission{hold: fmt.Sprintf("%d reloads within %s", len(history), cfg.FlapWindow.Duration)}
+	}
+	return admission{}
+}
+
+// hold suspends automatic reloads of w until an operator removes the hold annotation.
+func (r *reloader) hold(ctx context.Context, w workload.Workload, reason string) error {
+	obj := w.Object()
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+
+	setAnnotation(obj, annotation.Hold, reason)
+	// 운영자가 hold 를 해제하면 바로 다시 hold 되지 않도록 history 초기화
+	removeAnnotation(obj, annotation.ReloadHistory)
+
+	if err := r.client.Patch(ctx, obj, patch); err != nil {
+		return err
+	}
+
+	workloadHeld.WithLabelValues(obj.GetNamespace(), w.Kind(), obj.GetName()).Set(1)
+	holdsTotal.WithLabelValues(obj.GetNamespace(), w.Kind(), obj.GetName()).Inc()
+	r.recorder.Eventf(obj, corev1.EventTypeWarning, "ReloadHeld",
+		"Automatic reloads suspended after %s; remove the %s annotation to resume", reason, annotation.Hold)
+	log.FromContext(ctx).Info("workload is flapping, holding reloads", "workload", w.Kind()+"/"+obj.GetName(), "reason", reason)
+	return nil
+}
+
+// reloadHistory returns the reload times of obj within window before now, oldest first.
+func reloadHistory(obj client.Object, now time.Time, window time.Duration) []time.Time {
+	var history []time.Time
+	for _, v := range strings.Split(obj.GetAnnotations()[annotation.ReloadHistory], ",") {
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			continue
+		}
+		if t := time.Unix(sec, 0); now.Sub(t) < window {
+			history = append(history, t)
+		}
+	}
+	return history
+}
+
+// recordReload returns the reload history annotation of obj with now appended.
+func recordReload(obj client.Object, now time.Time, window time.Duration) string {
+	var values []string
+	for _, t := range reloadHistory(obj, now, window) {
+		values = append(values, strconv.FormatInt(t.Unix(), 10))
+	}
+	return strings.Join(append(values, strconv.FormatInt(now.Unix(), 10)), ",")
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+var _ = Describe("Flap detection", func() {
+	var (
+		r   *reloader
+		now time.Time
+	)
+
+	newWorkload := func(annotations map[string]string, reloadsAgo ...time.Duration) workload.Workload {
+		var history []string
+		for _, ago := range reloadsAgo {
+			history = append(history, strconv.FormatInt(now.Add(-ago).Unix(), 10))
+		}
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[annotation.ReloadHistory] = strings.Join(history, ",")
+
+		d := &appsv1.Deployment{}
+		d.Name = "app"
+		d.Namespace = "default"
+		d.Annotations = annotations
+		w, _ := workload.New(d)
+		return w
+	}
+
+	BeforeEach(func() {
+		r = &reloader{store: config.NewStore(config.NewConfig())}
+		now = time.Now().Truncate(time.Second)
+	})
+
+	It("admits a workload without history", func() {
+		Expect(r.admit(newWorkload(nil), nil, now)).To(Equal(admission{}))
+	})
+
+	It("waits for the cooldown to pass", func() {
+		decision := r.admit(newWorkload(nil, 10*time.Second), nil, now)
+		Expect(decision.wait).To(Equal(20 * time.Second))
+	})
+
+	It("honours the cooldown annotation", func() {
+		decision := r.admit(newWorkload(map[string]string{annotation.Cooldown: "1m"}, 10*time.Second), nil, now)
+		Expect(decision.wait).To(Equal(50 * time.Second))
+	})
+
+	It("holds a workload reloaded too often within the window", func() {
+		decision := r.admit(newWorkload(nil, 9*time.Minute, 7*time.Minute, 5*time.Minute, 3*time.Minute, time.Minute), nil, now)
+		Expect(decision.hold).NotTo(BeEmpty())
+	})
+
+	It("forgets reloads outside the window", func() {
+		decision := r.admit(newWorkload(nil, 30*time.Minute, 20*time.Minute, 5*time.Minute, 3*time.Minute, time.Minute), nil, now)
+		Expect(decision).To(Equal(admission{}))
+	})
+
+	It("skips a workload on hold", func() {
+		decision := r.admit(newWorkload(map[string]string{annotation.Hold: "flapping"}), nil, now)
+		Expect(decision.held).To(BeTrue())
+	})
+	It("drops the held series when the hold is removed or the workload deleted", func() {
+		held := newWorkload(map[string]string{annotation.Hold: "flapping"})
+		released := newWorkload(nil)
+		handler := r.workloadMetrics()
+		workloadHeld.Reset()
+
+		Expect(r.admit(held, nil, now).held).To(BeTrue())
+		Expect(testutil.CollectAndCount(workloadHeld)).To(Equal(1))
+		handler.Update(context.Background(), event.UpdateEvent{ObjectOld: held.Object(), ObjectNew: released.Object()}, nil)
+		Expect(testutil.CollectAndCount(workloadHeld)).To(BeZero())
+
+		Expect(r.admit(held, nil, now).held).To(BeTrue())
+		handler.Delete(context.Background(), event.DeleteEvent{Object: held.Object()}, nil)
+		Expect(testutil.CollectAndCount(workloadHeld)).To(BeZero())
+	})
+})
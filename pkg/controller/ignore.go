@@ -0,0 +1,79 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// ignoredChange returns why the change of src is system noise that must not
+// reload workloads, by the ignore rules of the controller config and of src.
+func (r *reloader) ignoredChange(src client.Object, attribution workload.Attribution, attributed bool) (string, bool) {
+	cfg := r.store.Get().Reload
+	return workload.IgnoredChange(src, attribution, attributed, cfg.IgnoreManagers, cfg.IgnoreManagedBy)
+}
+
+// absorbChange accepts an ignored change of src as the new baseline, so that
+// later reconciles do not reload for it either. Workloads that were up to
+// date before the change are stamped with their new hash without touching
+// the pod template, the others are left to reload on their own changes.
+// Ignored changes of critical sources still wait for approval.
+func (r *reloader) absorbChange(ctx context.Context, kind workload.SourceKind, src client.Object, change *sourceChange, workloads []workload.Workload) error {
+	for _, w := range workloads {
+		obj := w.Object()
+		annotations := obj.GetAnnotations()
+		if _, ok := annotations[annotation.DeferredReload]; ok {
+			continue
+		}
+		if _, ok := annotations[annotation.SyncingReload]; ok {
+			continue
+		}
+		prevHash, prevStatic, err := workload.HashAssuming(ctx, r.client, w, kind, src.GetName(), change.oldKeys)
+		if err != nil {
+			return err
+		}
+		if annotations[annotation.ConfigHash] != prevHash {
+			continue
+		}
+		hash, err := workload.Hash(ctx, r.client, w)
+		if err != nil {
+			return err
+		}
+		static, err := workload.StaticHash(ctx, r.client, w)
+		if err != nil {
+			return err
+		}
+		if hash == prevHash {
+			continue
+		}
+
+		patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+		setAnnotation(obj, annotation.ConfigHash, hash)
+		if annotations[annotation.StaticHash] == prevStatic {
+			setAnnotation(obj, annotation.StaticHash, static)
+		}
+		if err := r.client.Patch(ctx, obj, patch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
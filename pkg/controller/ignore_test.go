@@ -0,0 +1,195 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	reloaderv1alpha1 "github.com/hotkimho/reloader-server/project/api/v1alpha1"
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/approval"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+var _ = Describe("Ignored field managers", func() {
+	var (
+		ctx context.Context
+		r   *reloader
+		d   *appsv1.Deployment
+		cm  *corev1.ConfigMap
+		key = types.NamespacedName{Namespace: "default", Name: "web"}
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		d = &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name: "web", Namespace: "default", Annotations: map[string]string{annotation.Auto: "true"},
+		}}
+		d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "web", EnvFrom: []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web"}}},
+		}}}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Data:       map[string]string{"LOG_LEVEL": "info"},
+		}
+	})
+
+	// restart 는 같은 cluster 상태로 controller 를 다시 시작한 것처럼 reloader 를 새로 만듦
+	restart := func() {
+		cfg := config.NewConfig()
+		cfg.Reload.Cooldown = metav1.Duration{}
+		cfg.Reload.IgnoreManagers = []string{"*-leader-election"}
+		cfg.Reload.IgnoreManagedBy = []string{"cache-operator"}
+		r = &reloader{client: r.client, recorder: record.NewFakeRecorder(10), store: config.NewStore(cfg)}
+	}
+	build := func() {
+		r = &reloader{client: newFakeClient(d, cm, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})}
+		restart()
+	}
+	getDeployment := func() *appsv1.Deployment {
+		got := &appsv1.Deployment{}
+		Expect(r.client.Get(ctx, key, got)).To(Succeed())
+		return got
+	}
+	// change 는 manager 가 LOG_LEVEL 을 value 로 바꾼 것처럼 ConfigMap 을 갱신
+	change := func(manager, value string, mutate func(*corev1.ConfigMap)) {
+		Expect(r.client.Get(ctx, key, cm)).To(Succeed())
+		cm.Data["LOG_LEVEL"] = value
+		cm.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate,
+			Time: &metav1.Time{Time: metav1.Now().Time}, FieldsType: "FieldsV1",
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:LOG_LEVEL":{}}}`)}}}
+		if mutate != nil {
+			mutate(cm)
+		}
+		Expect(r.client.Update(ctx, cm)).To(Succeed())
+	}
+	reconcile := func() {
+		_, err := r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+	}
+	// start 는 workload 가 현재 내용으로 reload 된 상태에서 시작
+	start := func() string {
+		build()
+		reconcile()
+		change("kubectl-edit", "debug", nil)
+		reconcile()
+		restarted := getDeployment()
+		Expect(restarted.Spec.Template.Annotations).To(HaveKey(annotation.ConfigHash))
+		return restarted.Spec.Template.Annotations[annotation.ConfigHash]
+	}
+	// pod template 을 건드리지 않고 workload 의 hash 만 갱신했는지 확인
+	expectAbsorbed := func(template string) {
+		got := getDeployment()
+		Expect(got.Spec.Template.Annotations[annotation.ConfigHash]).To(Equal(template))
+		w, _ := workload.New(got)
+		hash, err := workload.Hash(ctx, r.client, w)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.Annotations[annotation.ConfigHash]).To(Equal(hash))
+		Expect(got.Annotations).NotTo(HaveKey(annotation.PendingReload))
+
+		list := &reloaderv1alpha1.ReloadRecordList{}
+		Expect(r.client.List(ctx, list)).To(Succeed())
+		Expect(list.Items).To(HaveLen(1))
+	}
+
+	It("does not reload for changes of ignored field managers", func() {
+		template := start()
+		change("kube-leader-election", "warn", nil)
+		reconcile()
+		expectAbsorbed(template)
+
+		// 이후 reconcile 에서도 무시한 변경으로 reload 하지 않음
+		reconcile()
+		expectAbsorbed(template)
+	})
+
+	It("keeps ignoring changes across controller restarts", func() {
+		template := start()
+		change("kube-leader-election", "warn", nil)
+		reconcile()
+		restart()
+		reconcile()
+		expectAbsorbed(template)
+
+		// controller 가 내려가 있는 동안의 변경도 무시
+		change("kube-leader-election", "error", nil)
+		restart()
+		reconcile()
+		expectAbsorbed(template)
+	})
+
+	It("honors the ignore-managers annotation of the source", func() {
+		template := start()
+		change("cache-writer", "warn", func(cm *corev1.ConfigMap) {
+			cm.Annotations = map[string]string{annotation.IgnoreManagers: "metrics-agent, cache-*"}
+		})
+		reconcile()
+		expectAbsorbed(template)
+	})
+
+	It("does not reload for changes of sources managed by ignored operators", func() {
+		template := start()
+		change("kubectl-edit", "warn", func(cm *corev1.ConfigMap) {
+			cm.Labels = map[string]string{"app.kubernetes.io/managed-by": "cache-operator"}
+		})
+		reconcile()
+		expectAbsorbed(template)
+	})
+
+	It("disregards annotation patterns matching any manager", func() {
+		template := start()
+		change("kubectl-edit", "warn", func(cm *corev1.ConfigMap) {
+			cm.Annotations = map[string]string{annotation.IgnoreManagers: "*"}
+		})
+		reconcile()
+		Expect(getDeployment().Spec.Template.Annotations[annotation.ConfigHash]).NotTo(Equal(template))
+	})
+
+	It("still requires approval of ignored changes to critical sources", func() {
+		cm.Labels = map[string]string{annotation.Critical: "true"}
+		cm.Annotations = map[string]string{
+			annotation.ApprovedHash: workload.ContentHash(workload.KeyHashes(map[string][]byte{"LOG_LEVEL": []byte("info")})),
+		}
+		build()
+		reconcile()
+		change("kube-leader-election", "warn", nil)
+		reconcile()
+
+		got := &corev1.ConfigMap{}
+		Expect(r.client.Get(ctx, key, got)).To(Succeed())
+		pending, ok := approval.GetPending(got)
+		Expect(ok).To(BeTrue())
+		Expect(got.Annotations[annotation.ApprovedHash]).NotTo(Equal(pending.Hash))
+	})
+
+	It("still reloads for changes of other field managers", func() {
+		template := start()
+		change("kubectl-edit", "warn", nil)
+		reconcile()
+		Expect(getDeployment().Spec.Template.Annotations[annotation.ConfigHash]).NotTo(Equal(template))
+	})
+})
@@ -0,0 +1,102 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=delete
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch
+
+// pullingWorkloads returns the governed workloads whose pods pull images
+// with the Secret of the given key, looked up through the pull secret indexes.
+func (r *reloader) pullingWorkloads(ctx context.Context, key types.NamespacedName, src client.Object) ([]workload.Workload, error) {
+	workloads, err := workload.PullingWith(ctx, r.client, key.Namespace, key.Name)
+	if err != nil {
+		return nil, err
+	}
+	var pulling []workload.Workload
+	for _, w := range workloads {
+		governed, err := r.governs(ctx, w, workload.Secret, src)
+		if err != nil {
+			return nil, err
+		}
+		if governed {
+			pulling = append(pulling, w)
+		}
+	}
+	return pulling, nil
+}
+
+// recoverImagePulls deletes the pods failing to pull their images in the
+// given workloads pulling with the registry credential Secret, so that they
+// are recreated with it. The workloads themselves are not rolled.
+// It is only called when the content of the Secret changed.
+func (r *reloader) recoverImagePulls(ctx context.Context, secret *corev1.Secret, pulling []workload.Workload) error {
+	logger := log.FromContext(ctx)
+	if secret.Type != corev1.SecretTypeDockerConfigJson && secret.Type != corev1.SecretTypeDockercfg {
+		return nil
+	}
+
+	for _, w := range pulling {
+		pods, err := workload.Pods(ctx, r.client, w)
+		if err != nil {
+			return err
+		}
+		var deleted []string
+		for i := range pods {
+			pod := &pods[i]
+			if pod.DeletionTimestamp != nil || !failingImagePull(pod) {
+				continue
+			}
+			if err := r.client.Delete(ctx, pod); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+			deleted = append(deleted, pod.Name)
+		}
+		if len(deleted) > 0 {
+			r.recorder.Eventf(w.Object(), corev1.EventTypeNormal, "ImagePullRecovered",
+				"Deleted %d pods failing to pull images (%s) after Secret/%s changed", len(deleted), strings.Join(deleted, ", "), secret.Name)
+			logger.Info("deleted pods failing image pulls", "workload", w.Kind()+"/"+w.Object().GetName(), "pods", deleted)
+		}
+	}
+	return nil
+}
+
+// failingImagePull reports whether a container of pod is waiting on an image pull error.
+func failingImagePull(pod *corev1.Pod) bool {
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, s := range statuses {
+		if s.State.Waiting == nil {
+			continue
+		}
+		switch s.State.Waiting.Reason {
+		case "ImagePullBackOff", "ErrImagePull":
+			return true
+		}
+	}
+	return false
+}
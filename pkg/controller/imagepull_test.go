@@ -0,0 +1,161 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+var _ = Describe("Image pull recovery", func() {
+	var (
+		ctx    context.Context
+		secret *corev1.Secret
+		labels = map[string]string{"app": "web"}
+	)
+
+	newDeployment := func(spec corev1.PodSpec) *appsv1.Deployment {
+		d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name: "web", Namespace: "default", Annotations: map[string]string{annotation.Auto: "true"},
+		}}
+		d.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+		d.Spec.Template.Spec = spec
+		return d
+	}
+	newPod := func(name, reason string) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels}}
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "web"}}
+		if reason != "" {
+			pod.Status.ContainerStatuses[0].State.Waiting = &corev1.ContainerStateWaiting{Reason: reason}
+		}
+		return pod
+	}
+	recoverPods := func(objs ...client.Object) client.Client {
+		c := newFakeClient(append(objs, secret)...)
+		r := &reloader{client: c, recorder: record.NewFakeRecorder(10), store: config.NewStore(config.NewConfig())}
+		pulling, err := r.pullingWorkloads(ctx, client.ObjectKeyFromObject(secret), secret)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.recoverImagePulls(ctx, secret, pulling)).To(Succeed())
+		return c
+	}
+	podNames := func(c client.Client) []string {
+		pods := &corev1.PodList{}
+		Expect(c.List(ctx, pods)).To(Succeed())
+		var names []string
+		for _, p := range pods.Items {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+		}
+	})
+
+	It("deletes only the pods failing image pulls", func() {
+		c := recoverPods(
+			newDeployment(corev1.PodSpec{ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}}}),
+			newPod("backoff", "ImagePullBackOff"), newPod("err", "ErrImagePull"), newPod("running", ""),
+		)
+		Expect(podNames(c)).To(ConsistOf("running"))
+	})
+
+	It("resolves the pull secrets of the service account", func() {
+		c := recoverPods(
+			newDeployment(corev1.PodSpec{ServiceAccountName: "builder"}),
+			&corev1.ServiceAccount{
+				ObjectMeta:       metav1.ObjectMeta{Name: "builder", Namespace: "default"},
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+			},
+			newPod("backoff", "ImagePullBackOff"),
+		)
+		Expect(podNames(c)).To(BeEmpty())
+	})
+
+	It("leaves workloads pulling with other secrets alone", func() {
+		c := recoverPods(
+			newDeployment(corev1.PodSpec{ImagePullSecrets: []corev1.LocalObjectReference{{Name: "other"}}}),
+			newPod("backoff", "ImagePullBackOff"),
+		)
+		Expect(podNames(c)).To(ConsistOf("backoff"))
+	})
+
+	It("only recovers pods when the content of the Secret changes", func() {
+		secret.Data = map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)}
+		c := newFakeClient(secret,
+			newDeployment(corev1.PodSpec{ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}}}),
+			newPod("backoff", "ImagePullBackOff"),
+		)
+		r := &reloader{client: c, recorder: record.NewFakeRecorder(10), store: config.NewStore(config.NewConfig())}
+		key := types.NamespacedName{Namespace: "default", Name: "registry"}
+		_, err := r.reconcileSource(ctx, workload.Secret, key)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = r.reconcileSource(ctx, workload.Secret, key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(podNames(c)).To(ConsistOf("backoff"))
+
+		Expect(c.Get(ctx, key, secret)).To(Succeed())
+		secret.Data[corev1.DockerConfigJsonKey] = []byte(`{"auths":{"registry.example.com":{}}}`)
+		Expect(c.Update(ctx, secret)).To(Succeed())
+		_, err = r.reconcileSource(ctx, workload.Secret, key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(podNames(c)).To(BeEmpty())
+	})
+	It("recovers pods for changes made while the controller was down", func() {
+		secret.Data = map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)}
+		c := newFakeClient(secret,
+			newDeployment(corev1.PodSpec{ServiceAccountName: "builder"}),
+			&corev1.ServiceAccount{
+				ObjectMeta:       metav1.ObjectMeta{Name: "builder", Namespace: "default"},
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+			},
+			newPod("backoff", "ImagePullBackOff"),
+		)
+		r := &reloader{client: c, recorder: record.NewFakeRecorder(10), store: config.NewStore(config.NewConfig())}
+		key := types.NamespacedName{Namespace: "default", Name: "registry"}
+		_, err := r.reconcileSource(ctx, workload.Secret, key)
+		Expect(err).NotTo(HaveOccurred())
+		d := &appsv1.Deployment{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web"}, d)).To(Succeed())
+		Expect(d.Annotations).To(HaveKey(annotation.ObservedSources))
+
+		Expect(c.Get(ctx, key, secret)).To(Succeed())
+		secret.Data[corev1.DockerConfigJsonKey] = []byte(`{"auths":{"registry.example.com":{}}}`)
+		Expect(c.Update(ctx, secret)).To(Succeed())
+		r = &reloader{client: c, recorder: record.NewFakeRecorder(10), store: config.NewStore(config.NewConfig())}
+		_, err = r.reconcileSource(ctx, workload.Secret, key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(podNames(c)).To(BeEmpty())
+	})
+})
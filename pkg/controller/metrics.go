@@ -0,0 +1,119 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+var workloadLabels = []string{"namespace", "kind", "name"}
+
+var (
+	reloadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "reloader_reloads_total",
+		Help: "Number of reloads triggered per workload and strategy",
+	}, append(workloadLabels, "strategy"))
+
+	inPlaceFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "reloader_in_place_failures_total",
+		Help: "Number of in-place reloads that fell back to a restart",
+	}, append(workloadLabels, "strategy"))
+
+	holdsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "reloader_holds_total",
+		Help: "Number of times a workload was put on hold by the flap detector",
+	}, workloadLabels)
+
+	workloadHeld = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "reloader_workload_held",
+		Help: "Whether automatic reloads of a workload are on hold (1) or not",
+	}, workloadLabels)
+
+	certificateExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "reloader_certificate_expiry_timestamp_seconds",
+		Help: "Expiry of the earliest expiring certificate of a TLS Secret, as a unix timestamp",
+	}, []string{"namespace", "secret"})
+
+	workloadCertificateExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "reloader_workload_certificate_expiry_timestamp_seconds",
+		Help: "Expiry of the earliest expiring certificate consumed by a workload, as a unix timestamp",
+	}, workloadLabels)
+
+	// source 이름은 cardinality 가 커서 label 에서 제외, manager 는 managerLabel 로 제한
+	sourceChangesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "reloader_source_changes_total",
+		Help: "Number of observed ConfigMap and Secret content changes per field manager",
+	}, []string{"namespace", "kind", "manager"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(reloadsTotal, inPlaceFailuresTotal, holdsTotal, workloadHeld,
+		certificateExpiry, workloadCertificateExpiry, sourceChangesTotal)
+}
+
+// managerLabel returns the manager label of sourceChangesTotal: the field
+// manager when the config lists it, "other" otherwise.
+func (r *reloader) managerLabel(manager string) string {
+	if slices.Contains(r.store.Get().Reload.MetricManagers, manager) {
+		return manager
+	}
+	return "other"
+}
+
+// workloadMetrics keeps the per workload series in line with the workloads:
+// the hold series is dropped when an operator removes the hold, the
+// certificate series follows the TLS Secrets the workload references, and
+// every series is dropped when the workload is deleted.
+func (r *reloader) workloadMetrics() handler.EventHandler {
+	return handler.Funcs{
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, _ workqueue.RateLimitingInterface) {
+			w, ok := workload.New(e.ObjectNew)
+			if !ok {
+				return
+			}
+			obj := w.Object()
+			_, before := e.ObjectOld.GetAnnotations()[annotation.Hold]
+			_, after := obj.GetAnnotations()[annotation.Hold]
+			if before && !after {
+				workloadHeld.DeleteLabelValues(obj.GetNamespace(), w.Kind(), obj.GetName())
+			}
+			if err := r.observeWorkloadCertificates(ctx, w); err != nil {
+				log.FromContext(ctx).Error(err, "unable to observe certificate expiry", "workload", w.Kind()+"/"+obj.GetName())
+			}
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, _ workqueue.RateLimitingInterface) {
+			w, ok := workload.New(e.Object)
+			if !ok {
+				return
+			}
+			obj := w.Object()
+			workloadHeld.DeleteLabelValues(obj.GetNamespace(), w.Kind(), obj.GetName())
+			workloadCertificateExpiry.DeleteLabelValues(obj.GetNamespace(), w.Kind(), obj.GetName())
+		},
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/hotkimho/reloader-server/project/pkg/notify"
+)
+
+// announce records an Event on obj and sends it as a notification when a
+// notification webhook is configured. Notifications are sent in the
+// background, failed ones are only logged.
+func (r *reloader) announce(ctx context.Context, obj client.Object, kind, eventType, reason, message string, details map[string]string) {
+	r.recorder.Event(obj, eventType, reason, message)
+
+	cfg := r.store.Get().Notifications
+	if cfg.WebhookURL == "" {
+		return
+	}
+	notifier := &notify.Webhook{URL: cfg.WebhookURL, Client: &http.Client{Timeout: cfg.Timeout.Duration}}
+	n := notify.Notification{
+		Reason:    reason,
+		Message:   message,
+		Namespace: obj.GetNamespace(),
+		Kind:      kind,
+		Name:      obj.GetName(),
+		Time:      time.Now().UTC(),
+		Details:   details,
+	}
+	// 느린 webhook 이 reconcile 을 붙잡지 않도록 따로 전송, client 의 timeout 으로 제한됨
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := notifier.Notify(ctx, n); err != nil {
+			log.FromContext(ctx).Error(err, "unable to send notification", "reason", reason)
+		}
+	}()
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+const (
+	reasonPaused           = "reloads paused"
+	reasonAwaitingApproval = "awaiting approval after pause, set " + annotation.Approve + "=true to apply"
+)
+
+// NamespaceReconciler resumes the pending reloads of a namespace once it is
+// unpaused or its maintenance windows change
+type NamespaceReconciler struct {
+	*reloader
+}
+
+func (r *NamespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ns := &corev1.Namespace{}
+	if err := r.client.Get(ctx, req.NamespacedName, ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if r.paused(ns) {
+		return ctrl.Result{}, nil
+	}
+	return r.resumePending(ctx, ns.Name)
+}
+
+// reconcileConfig reloads the controller config, resumes the pending reloads
+// when it is unpaused and releases the sources when their in-use protection
+// is disabled.
+func (r *reloader) reconcileConfig(ctx context.Context) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	cm := &corev1.ConfigMap{}
+	if err := r.client.Get(ctx, r.store.Key, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("config map deleted, keeping the current config")
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	prev, err := r.store.Update(cm)
+	if err != nil {
+		logger.Error(err, "unable to reload config, keeping the current config")
+		r.recorder.Eventf(cm, corev1.EventTypeWarning, "InvalidConfig", "Unable to reload config: %v", err)
+		return ctrl.Result{}, nil
+	}
+	logger.Info("reloaded config", "paused", r.store.Get().Reload.Paused)
+
+	if prev.Reload.ProtectInUse && !r.store.Get().Reload.ProtectInUse {
+		if err := r.unprotectAll(ctx); err != nil {
+			logger.Error(err, "unable to remove in-use protection")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if prev.Reload.Paused && !r.store.Get().Reload.Paused {
+		return r.resumePending(ctx, "")
+	}
+	return ctrl.Result{}, nil
+}
+
+// paused reports whether reloads are paused globally or in ns.
+func (r *reloader) paused(ns *corev1.Namespace) bool {
+	return r.store.Get().Reload.Paused || ns.Annotations[annotation.Paused] == "true"
+}
+
+// awaitingApproval reports whether the reload of w, queued while paused,
+// waits for an operator to approve it.
+func (r *reloader) awaitingApproval(w workload.Workload) bool {
+	if !r.store.Get().Reload.ResumeApproval {
+		return false
+	}
+	pending, ok := getPendingReload(w.Object())
+	if !ok || (pending.Reason != reasonPaused && pending.Reason != reasonAwaitingApproval) {
+		return false
+	}
+	return w.Object().GetAnnotations()[annotation.Approve] != "true"
+}
+
+// resumePending re-evaluates every queued reload in namespace, or in all
+// namespaces when it is empty.
+func (r *reloader) resumePending(ctx context.Context, namespace string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	workloads, err := workload.List(ctx, r.client, namespace)
+	if err != nil {
+		logger.Error(err, "unable to list workloads")
+		return ctrl.Result{}, err
+	}
+
+	var (
+		result   ctrl.Result
+		approval []string
+	)
+	for _, w := range workloads {
+		if _, ok := getPendingReload(w.Object()); !ok {
+			continue
+		}
+		governed, err := r.governs(ctx, w, "", nil)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !governed {
+			continue
+		}
+
+		var wait time.Duration
+		if wait, err = r.reconcileWorkload(ctx, w, "queued reload resumed"); err != nil {
+			logger.Error(err, "unable to resume reload", "workload", w.Kind()+"/"+w.Object().GetName())
+			return ctrl.Result{}, err
+		}
+		result.RequeueAfter = minRequeue(result.RequeueAfter, wait)
+
+		if pending, ok := getPendingReload(w.Object()); ok && pending.Reason == reasonAwaitingApproval {
+			approval = append(approval, w.Object().GetNamespace()+"/"+w.Kind()+"/"+w.Object().GetName())
+		}
+	}
+
+	if len(approval) > 0 {
+		logger.Info("reloads awaiting approval", "workloads", approval)
+	}
+	return result, nil
+}
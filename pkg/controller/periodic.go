@@ -0,0 +1,191 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/window"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// scheduledRestart is the value of the scheduled restart annotation.
+type scheduledRestart struct {
+	// Schedule is the schedule Next was computed for.
+	Schedule string     `json:"schedule"`
+	Last     *time.Time `json:"last,omitempty"`
+	Next     time.Time  `json:"next"`
+}
+
+// ScheduleReconciler reloads the workloads of one kind on their restart schedule.
+type ScheduleReconciler struct {
+	*reloader
+	object func() client.Object
+}
+
+func (r *ScheduleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	obj := r.object()
+	if err := r.client.Get(ctx, req.NamespacedName, obj); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	w, ok := workload.New(obj)
+	if !ok || w.Kind() == workload.CronJob {
+		return ctrl.Result{}, nil
+	}
+	wait, err := r.reconcileSchedule(ctx, w, time.Now())
+	return ctrl.Result{RequeueAfter: wait}, err
+}
+
+// reconcileSchedule reloads w when its scheduled restart is due, and returns
+// how long to wait for the next one.
+func (r *reloader) reconcileSchedule(ctx context.Context, w workload.Workload, now time.Time) (time.Duration, error) {
+	obj := w.Object()
+	spec, ok := obj.GetAnnotations()[annotation.RestartSchedule]
+	if !ok {
+		if _, ok := obj.GetAnnotations()[annotation.ScheduledRestart]; !ok {
+			return 0, nil
+		}
+		patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+		removeAnnotation(obj, annotation.ScheduledRestart)
+		return 0, r.client.Patch(ctx, obj, patch)
+	}
+
+	recurrence, err := window.NewRecurrence(spec, r.jitter(w))
+	if err != nil {
+		r.recorder.Eventf(obj, corev1.EventTypeWarning, "InvalidRestartSchedule", "%v", err)
+		return 0, nil
+	}
+
+	state, _ := getScheduledRestart(obj)
+	var last time.Time
+	if state.Last != nil {
+		last = *state.Last
+	}
+	if state.Schedule != spec || state.Next.IsZero() {
+		state = scheduledRestart{Schedule: spec, Last: state.Last, Next: recurrence.Next(last, now)}
+		return state.Next.Sub(now), r.setScheduledRestart(ctx, w, state)
+	}
+	if now.Before(state.Next) {
+		return state.Next.Sub(now), nil
+	}
+
+	s, err := r.policies(ctx, w)
+	if err != nil {
+		return 0, err
+	}
+	// 변경에 의한 reload 와 같이 cooldown, flap detection 을 따름
+	decision := r.admit(w, s, now)
+
+	ns := &corev1.Namespace{}
+	if err := r.client.Get(ctx, types.NamespacedName{Name: obj.GetNamespace()}, ns); err != nil {
+		return 0, err
+	}
+	if decision.held || r.paused(ns) {
+		// 이번 회차는 건너뛰고 다음 회차로
+		log.FromContext(ctx).Info("skipping scheduled restart of a held or paused workload", "workload", w.Kind()+"/"+obj.GetName())
+		state.Next = recurrence.Next(time.Time{}, now)
+		return state.Next.Sub(now), r.setScheduledRestart(ctx, w, state)
+	}
+
+	// 변경에 의한 reload 와 같이 maintenance window 와 freeze 를 따름
+	schedule, err := r.schedule(ns, s)
+	if err != nil {
+		return 0, err
+	}
+	if !schedule.Allowed(now) {
+		next := schedule.Next(now)
+		if next.IsZero() {
+			log.FromContext(ctx).Info("skipping scheduled restart without an upcoming maintenance window", "workload", w.Kind()+"/"+obj.GetName())
+			state.Next = recurrence.Next(time.Time{}, now)
+			return state.Next.Sub(now), r.setScheduledRestart(ctx, w, state)
+		}
+		log.FromContext(ctx).V(1).Info("delaying scheduled restart outside maintenance window", "workload", w.Kind()+"/"+obj.GetName(), "until", next)
+		return next.Sub(now), nil
+	}
+
+	switch {
+	case decision.hold != "":
+		// hold 된 workload 는 다음 reconcile 에서 이번 회차를 건너뜀
+		return 0, r.hold(ctx, w, decision.hold)
+	case decision.wait > 0:
+		log.FromContext(ctx).V(1).Info("delaying scheduled restart until the cooldown passes", "workload", w.Kind()+"/"+obj.GetName(), "wait", decision.wait)
+		return decision.wait, nil
+	}
+
+	hash, err := workload.Hash(ctx, r.client, w)
+	if err != nil {
+		return 0, err
+	}
+	wait, err := r.apply(ctx, w, s, hash, "scheduled restart ("+spec+")", now)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+
+	ran := now.UTC().Truncate(time.Second)
+	state.Last = &ran
+	state.Next = recurrence.Next(ran, now)
+	return state.Next.Sub(now), r.setScheduledRestart(ctx, w, state)
+}
+
+// jitter returns the fixed delay of the scheduled reloads of w, spread
+// between zero and the configured jitter by the name of w.
+func (r *reloader) jitter(w workload.Workload) time.Duration {
+	max := r.store.Get().Reload.RestartJitter.Duration
+	if v, ok := w.Object().GetAnnotations()[annotation.RestartJitter]; ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			max = d
+		}
+	}
+	if max < time.Second {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(w.Kind() + "/" + w.Object().GetNamespace() + "/" + w.Object().GetName()))
+	return time.Duration(h.Sum64()%uint64(max/time.Second)) * time.Second
+}
+
+func (r *reloader) setScheduledRestart(ctx context.Context, w workload.Workload, state scheduledRestart) error {
+	obj := w.Object()
+
+	state.Next = state.Next.UTC()
+	value, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	setAnnotation(obj, annotation.ScheduledRestart, string(value))
+	return r.client.Patch(ctx, obj, patch)
+}
+
+func getScheduledRestart(obj client.Object) (scheduledRestart, bool) {
+	var state scheduledRestart
+	v, ok := obj.GetAnnotations()[annotation.ScheduledRestart]
+	if !ok || json.Unmarshal([]byte(v), &state) != nil {
+		return scheduledRestart{}, false
+	}
+	return state, true
+}
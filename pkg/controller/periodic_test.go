@@ -0,0 +1,134 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+var _ = Describe("Scheduled restarts", func() {
+	var (
+		ctx context.Context
+		r   *reloader
+		d   *appsv1.Deployment
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Now().UTC().Truncate(time.Second)
+		d = &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name: "legacy", Namespace: "default",
+			Annotations: map[string]string{annotation.RestartSchedule: "24h", annotation.RestartJitter: "0s"},
+		}}
+		c := newFakeClient(d, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+		r = &reloader{client: c, recorder: record.NewFakeRecorder(10), store: config.NewStore(config.NewConfig())}
+	})
+
+	It("records the next restart and restarts when it is due", func() {
+		w, _ := workload.New(d)
+		wait, err := r.reconcileSchedule(ctx, w, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wait).To(Equal(24 * time.Hour))
+		state, ok := getScheduledRestart(d)
+		Expect(ok).To(BeTrue())
+		Expect(state.Last).To(BeNil())
+		Expect(state.Next).To(BeTemporally("==", now.Add(24*time.Hour)))
+
+		due := state.Next
+		wait, err = r.reconcileSchedule(ctx, w, due)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wait).To(Equal(24 * time.Hour))
+		Expect(d.Spec.Template.Annotations).To(HaveKeyWithValue(annotation.RestartedAt, due.Format(time.RFC3339)))
+		state, _ = getScheduledRestart(d)
+		Expect(*state.Last).To(BeTemporally("==", due))
+		Expect(state.Next).To(BeTemporally("==", due.Add(24*time.Hour)))
+	})
+
+	It("waits for the cooldown after a recent reload", func() {
+		w, _ := workload.New(d)
+		_, err := r.reconcileSchedule(ctx, w, now)
+		Expect(err).NotTo(HaveOccurred())
+		due := now.Add(24 * time.Hour)
+		d.Annotations[annotation.ReloadHistory] = strconv.FormatInt(due.Add(-10*time.Second).Unix(), 10)
+
+		wait, err := r.reconcileSchedule(ctx, w, due)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wait).To(Equal(20 * time.Second))
+		Expect(d.Spec.Template.Annotations).NotTo(HaveKey(annotation.RestartedAt))
+	})
+
+	It("holds a flapping workload instead of restarting it", func() {
+		w, _ := workload.New(d)
+		_, err := r.reconcileSchedule(ctx, w, now)
+		Expect(err).NotTo(HaveOccurred())
+		due := now.Add(24 * time.Hour)
+		var history []string
+		for i := 5; i > 0; i-- {
+			history = append(history, strconv.FormatInt(due.Add(-time.Duration(i)*time.Minute).Unix(), 10))
+		}
+		d.Annotations[annotation.ReloadHistory] = strings.Join(history, ",")
+
+		_, err = r.reconcileSchedule(ctx, w, due)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Annotations).To(HaveKey(annotation.Hold))
+		Expect(d.Spec.Template.Annotations).NotTo(HaveKey(annotation.RestartedAt))
+	})
+
+	It("waits for the maintenance window", func() {
+		r.store.Get().Windows.Allowed = []config.MaintenanceWindow{{
+			Schedule: "0 3 * * *", Duration: metav1.Duration{Duration: time.Hour},
+		}}
+		w, _ := workload.New(d)
+		_, err := r.reconcileSchedule(ctx, w, now)
+		Expect(err).NotTo(HaveOccurred())
+		state, _ := getScheduledRestart(d)
+
+		// 03:00 이전에 도래한 회차는 window 가 열릴 때까지 미룸
+		due := time.Date(state.Next.Year(), state.Next.Month(), state.Next.Day()+1, 1, 0, 0, 0, time.Local)
+		wait, err := r.reconcileSchedule(ctx, w, due)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wait).To(Equal(2 * time.Hour))
+		Expect(d.Spec.Template.Annotations).NotTo(HaveKey(annotation.RestartedAt))
+
+		wait, err = r.reconcileSchedule(ctx, w, due.Add(wait))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Spec.Template.Annotations).To(HaveKey(annotation.RestartedAt))
+		Expect(wait).To(BeNumerically(">", 0))
+	})
+
+	It("spreads workloads within the jitter", func() {
+		delete(d.Annotations, annotation.RestartJitter)
+		w, _ := workload.New(d)
+		Expect(r.jitter(w)).To(And(BeNumerically(">=", 0), BeNumerically("<", 5*time.Minute)))
+	})
+})
@@ -0,0 +1,152 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	reloaderv1alpha1 "github.com/hotkimho/reloader-server/project/api/v1alpha1"
+	"github.com/hotkimho/reloader-server/project/pkg/policy"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// +kubebuilder:rbac:groups=reloader.accordions.edu,resources=reloadpolicies;clusterreloadpolicies,verbs=get;list;watch
+
+// reasonDebounce is the reason of a reload waiting for its sources to settle.
+const reasonDebounce = "waiting for sources to settle"
+
+// policies returns the settings merged from the ReloadPolicies and
+// ClusterReloadPolicies selecting w, or nil when none does.
+func (r *reloader) policies(ctx context.Context, w workload.Workload) (*policy.Settings, error) {
+	return policy.Select(ctx, r.client, w)
+}
+
+// governs reports whether changes to src reload w, because w opted in by
+// annotation or a policy selects both. A nil src stands for any source.
+func (r *reloader) governs(ctx context.Context, w workload.Workload, kind workload.SourceKind, src client.Object) (bool, error) {
+	if optedIn(w) {
+		return true, nil
+	}
+	s, err := r.policies(ctx, w)
+	if err != nil || s.Empty() {
+		return false, err
+	}
+	if src == nil {
+		return true, nil
+	}
+	return s.Governs(kind, src.GetName(), src.GetLabels()), nil
+}
+
+// debounce holds back the reload of w to hash until the sources stayed
+// unchanged for the debounce of s, and returns how long is left to wait.
+func (r *reloader) debounce(ctx context.Context, w workload.Workload, s *policy.Settings, hash string, now time.Time) (time.Duration, error) {
+	delay := s.Debounce()
+	if delay <= 0 {
+		return 0, nil
+	}
+	pending, ok := getPendingReload(w.Object())
+	switch {
+	case !ok || pending.Hash != hash:
+		// source 가 바뀔 때마다 대기 시간을 다시 시작
+		return delay, r.queue(ctx, w, hash, reasonDebounce, now.Add(delay))
+	case pending.Reason == reasonDebounce && pending.NotBefore != nil && now.Before(*pending.NotBefore):
+		return pending.NotBefore.Sub(now), nil
+	}
+	return 0, nil
+}
+
+// policySources maps a ReloadPolicy to the sources of the given kind it
+// selects in its namespace, so that a new or changed policy is applied.
+func (r *reloader) policySources(kind workload.SourceKind) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		p, ok := obj.(*reloaderv1alpha1.ReloadPolicy)
+		if !ok {
+			return nil
+		}
+		return r.selectedSources(ctx, kind, p.Namespace, &p.Spec.PolicyRules)
+	}
+}
+
+// clusterPolicySources maps a ClusterReloadPolicy to the sources of the
+// given kind it selects in the namespaces it selects.
+func (r *reloader) clusterPolicySources(kind workload.SourceKind) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		p, ok := obj.(*reloaderv1alpha1.ClusterReloadPolicy)
+		if !ok {
+			return nil
+		}
+		namespaces, err := r.selectedNamespaces(ctx, &p.Spec)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "unable to list namespaces of policy", "policy", p.Name)
+			return nil
+		}
+		var reqs []reconcile.Request
+		for _, ns := range namespaces {
+			reqs = append(reqs, r.selectedSources(ctx, kind, ns, &p.Spec.PolicyRules)...)
+		}
+		return reqs
+	}
+}
+
+// selectedSources returns requests for the sources of the given kind in
+// namespace selected by rules.
+func (r *reloader) selectedSources(ctx context.Context, kind workload.SourceKind, namespace string, rules *reloaderv1alpha1.PolicyRules) []reconcile.Request {
+	list := workload.NewSourceList(kind)
+	if err := r.client.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list sources of policy", "namespace", namespace)
+		return nil
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for _, item := range items {
+		src := item.(client.Object)
+		if policy.MatchesSource(rules, kind, src.GetName(), src.GetLabels()) {
+			reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(src)})
+		}
+	}
+	return reqs
+}
+
+// selectedNamespaces returns the names of the namespaces selected by spec.
+func (r *reloader) selectedNamespaces(ctx context.Context, spec *reloaderv1alpha1.ClusterReloadPolicySpec) ([]string, error) {
+	list := &corev1.NamespaceList{}
+	if err := r.client.List(ctx, list); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, ns := range list.Items {
+		if ns.DeletionTimestamp == nil && policy.MatchesNamespace(spec, ns.Labels) {
+			names = append(names, ns.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
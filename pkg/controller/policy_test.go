@@ -0,0 +1,247 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	reloaderv1alpha1 "github.com/hotkimho/reloader-server/project/api/v1alpha1"
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+var _ = Describe("Reload policies", func() {
+	var (
+		ctx context.Context
+		r   *reloader
+		d   *appsv1.Deployment
+		p   *reloaderv1alpha1.ReloadPolicy
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		d = &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name: "web", Namespace: "default", Labels: map[string]string{"tier": "web"},
+		}}
+		d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "web", EnvFrom: []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}}},
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "db-config"}}},
+		}}}
+		p = &reloaderv1alpha1.ReloadPolicy{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		p.Spec.Sources = []reloaderv1alpha1.SourceSelector{{Kind: "ConfigMap", Names: []string{"app-*"}}}
+		p.Spec.Targets = []reloaderv1alpha1.TargetSelector{{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "web"}}}}
+		p.Spec.Debounce = &metav1.Duration{Duration: 30 * time.Second}
+	})
+
+	build := func() {
+		c := newFakeClient(d, p,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "db-config", Namespace: "default"}},
+		)
+		r = &reloader{client: c, recorder: record.NewFakeRecorder(10), store: config.NewStore(config.NewConfig())}
+	}
+	get := func() *appsv1.Deployment {
+		got := &appsv1.Deployment{}
+		Expect(r.client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web"}, got)).To(Succeed())
+		return got
+	}
+
+	It("debounces changes of the selected sources", func() {
+		build()
+		result, err := r.reconcileSource(ctx, "ConfigMap", types.NamespacedName{Namespace: "default", Name: "app-config"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(30 * time.Second))
+
+		pending, ok := getPendingReload(get())
+		Expect(ok).To(BeTrue())
+		Expect(pending.Reason).To(Equal(reasonDebounce))
+	})
+
+	It("ignores sources the policy does not select", func() {
+		build()
+		result, err := r.reconcileSource(ctx, "ConfigMap", types.NamespacedName{Namespace: "default", Name: "db-config"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(get().Annotations).NotTo(HaveKey(annotation.PendingReload))
+	})
+
+	It("ignores workloads the policy does not target", func() {
+		d.Labels["tier"] = "db"
+		build()
+		_, err := r.reconcileSource(ctx, "ConfigMap", types.NamespacedName{Namespace: "default", Name: "app-config"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(get().Annotations).NotTo(HaveKey(annotation.PendingReload))
+	})
+
+	It("enqueues the policies selecting a source by its labels", func() {
+		p.Spec.Sources = []reloaderv1alpha1.SourceSelector{{Kind: "ConfigMap", Selector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"reload": "true"},
+		}}}
+		build()
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "db-config", Namespace: "default"}}
+		Expect(r.namespacePolicies(ctx, cm)).To(BeEmpty())
+
+		cm.Labels = map[string]string{"reload": "true"}
+		Expect(r.namespacePolicies(ctx, cm)).To(Equal([]reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}}))
+
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db-config", Namespace: "default", Labels: cm.Labels}}
+		Expect(r.namespacePolicies(ctx, secret)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Cluster reload policies", func() {
+	var (
+		ctx context.Context
+		r   *reloader
+		d   *appsv1.Deployment
+		ns  *corev1.Namespace
+		crp *reloaderv1alpha1.ClusterReloadPolicy
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		d = &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "tenant-a"}}
+		d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "web", EnvFrom: []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}}},
+		}}}
+		ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", Labels: map[string]string{"tenant": "true"}}}
+		crp = &reloaderv1alpha1.ClusterReloadPolicy{ObjectMeta: metav1.ObjectMeta{Name: "tenants", Generation: 2}}
+		crp.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}}
+		crp.Spec.Sources = []reloaderv1alpha1.SourceSelector{{Kind: "ConfigMap"}}
+		crp.Spec.Strategy = "signal"
+	})
+
+	build := func(objs ...client.Object) {
+		c := newFakeClient(append([]client.Object{d, ns, crp,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "tenant-a"}},
+		}, objs...)...)
+		r = &reloader{client: c, recorder: record.NewFakeRecorder(10), store: config.NewStore(config.NewConfig())}
+	}
+
+	It("applies to workloads of the selected namespaces", func() {
+		build()
+		w, _ := workload.New(d)
+		s, err := r.policies(ctx, w)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Policies).To(Equal([]string{"ClusterReloadPolicy/tenants"}))
+
+		ns.Labels = nil
+		build()
+		s, err = r.policies(ctx, w)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Empty()).To(BeTrue())
+	})
+
+	It("yields to the ReloadPolicies of the namespace", func() {
+		p := &reloaderv1alpha1.ReloadPolicy{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "tenant-a"}}
+		p.Spec.Sources = []reloaderv1alpha1.SourceSelector{{Kind: "ConfigMap"}}
+		p.Spec.Strategy = "restart"
+		crp.Spec.Priority = 100
+		crp.Spec.Cooldown = &metav1.Duration{Duration: time.Minute}
+		build(p)
+
+		w, _ := workload.New(d)
+		s, err := r.policies(ctx, w)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Policies).To(Equal([]string{"ReloadPolicy/web", "ClusterReloadPolicy/tenants"}))
+		Expect(s.Annotations(nil)).To(Equal(map[string]string{
+			annotation.Strategy: "restart",
+			annotation.Cooldown: "1m0s",
+		}))
+	})
+
+	It("reports the matched namespaces and workloads", func() {
+		build()
+		sr := &ClusterPolicyStatusReconciler{r}
+		_, err := sr.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "tenants"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		got := &reloaderv1alpha1.ClusterReloadPolicy{}
+		Expect(r.client.Get(ctx, types.NamespacedName{Name: "tenants"}, got)).To(Succeed())
+		Expect(got.Status).To(Equal(reloaderv1alpha1.ClusterReloadPolicyStatus{
+			ObservedGeneration:   2,
+			MatchedNamespaces:    []string{"tenant-a"},
+			MatchedWorkloads:     []reloaderv1alpha1.MatchedWorkload{{Namespace: "tenant-a", Kind: "Deployment", Name: "web"}},
+			MatchedWorkloadCount: 1,
+		}))
+	})
+
+	It("enqueues only the policies matching a workload", func() {
+		other := &reloaderv1alpha1.ClusterReloadPolicy{ObjectMeta: metav1.ObjectMeta{Name: "others"}}
+		other.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "false"}}
+		other.Spec.Sources = []reloaderv1alpha1.SourceSelector{{Kind: "ConfigMap"}}
+		build(other)
+
+		Expect(r.clusterPolicies(ctx, d)).To(Equal([]reconcile.Request{{NamespacedName: types.NamespacedName{Name: "tenants"}}}))
+		Expect(r.clusterPolicies(ctx, ns)).To(Equal([]reconcile.Request{{NamespacedName: types.NamespacedName{Name: "tenants"}}}))
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "tenant-a"}}
+		Expect(r.clusterPolicies(ctx, cm)).To(Equal([]reconcile.Request{{NamespacedName: types.NamespacedName{Name: "tenants"}}}))
+	})
+
+	It("keeps the ReloadPolicies of the namespace when the namespace is gone", func() {
+		p := &reloaderv1alpha1.ReloadPolicy{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "gone"}}
+		p.Spec.Sources = []reloaderv1alpha1.SourceSelector{{Kind: "ConfigMap"}}
+		p.Spec.Strategy = "restart"
+		gone := d.DeepCopy()
+		gone.Namespace = "gone"
+		build(p, gone)
+
+		w, _ := workload.New(gone)
+		s, err := r.policies(ctx, w)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Policies).To(Equal([]string{"ReloadPolicy/web"}))
+	})
+})
+
+var _ = Describe("Reload policy status", func() {
+	It("reports the workloads consuming a selected source", func() {
+		ctx := context.Background()
+		web := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		web.Spec.Template.Spec.Containers = []corev1.Container{{Name: "web", EnvFrom: []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}}},
+		}}}
+		db := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}}
+		p := &reloaderv1alpha1.ReloadPolicy{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 1}}
+		p.Spec.Sources = []reloaderv1alpha1.SourceSelector{{Kind: "ConfigMap", Names: []string{"app-*"}}}
+		r := &reloader{client: newFakeClient(web, db, p), recorder: record.NewFakeRecorder(10), store: config.NewStore(config.NewConfig())}
+
+		sr := &PolicyStatusReconciler{r}
+		_, err := sr.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		got := &reloaderv1alpha1.ReloadPolicy{}
+		Expect(r.client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web"}, got)).To(Succeed())
+		Expect(got.Status.ObservedGeneration).To(Equal(int64(1)))
+		Expect(got.Status.MatchedWorkloads).To(Equal([]reloaderv1alpha1.MatchedWorkload{{Kind: "Deployment", Name: "web"}}))
+	})
+})
@@ -0,0 +1,260 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	reloaderv1alpha1 "github.com/hotkimho/reloader-server/project/api/v1alpha1"
+	"github.com/hotkimho/reloader-server/project/pkg/policy"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// +kubebuilder:rbac:groups=reloader.accordions.edu,resources=reloadpolicies/status;clusterreloadpolicies/status,verbs=get;update;patch
+
+// maxMatchedWorkloads bounds the workloads listed in the status of a policy.
+const maxMatchedWorkloads = 100
+
+// PolicyStatusReconciler reports the workloads a ReloadPolicy matches
+type PolicyStatusReconciler struct {
+	*reloader
+}
+
+// ClusterPolicyStatusReconciler reports the namespaces and workloads a
+// ClusterReloadPolicy matches
+type ClusterPolicyStatusReconciler struct {
+	*reloader
+}
+
+func (r *PolicyStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	p := &reloaderv1alpha1.ReloadPolicy{}
+	if err := r.client.Get(ctx, req.NamespacedName, p); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if p.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	matched, err := r.matchedWorkloads(ctx, p.Namespace, &p.Spec.PolicyRules, false)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	status := reloaderv1alpha1.ReloadPolicyStatus{ObservedGeneration: p.Generation}
+	status.MatchedWorkloadCount, status.MatchedWorkloads = capMatched(matched)
+	if equality.Semantic.DeepEqual(p.Status, status) {
+		return ctrl.Result{}, nil
+	}
+	p.Status = status
+	return ctrl.Result{}, r.client.Status().Update(ctx, p)
+}
+
+func (r *ClusterPolicyStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	p := &reloaderv1alpha1.ClusterReloadPolicy{}
+	if err := r.client.Get(ctx, req.NamespacedName, p); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if p.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	namespaces, err := r.selectedNamespaces(ctx, &p.Spec)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	status := reloaderv1alpha1.ClusterReloadPolicyStatus{ObservedGeneration: p.Generation, MatchedNamespaces: namespaces}
+	var all []reloaderv1alpha1.MatchedWorkload
+	for _, ns := range namespaces {
+		matched, err := r.matchedWorkloads(ctx, ns, &p.Spec.PolicyRules, true)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		all = append(all, matched...)
+	}
+	status.MatchedWorkloadCount, status.MatchedWorkloads = capMatched(all)
+	if equality.Semantic.DeepEqual(p.Status, status) {
+		return ctrl.Result{}, nil
+	}
+	p.Status = status
+	return ctrl.Result{}, r.client.Status().Update(ctx, p)
+}
+
+// matchedWorkloads returns the workloads in namespace selected by the targets
+// of rules that consume a source selected by rules. The namespace is only
+// reported for cluster-scoped policies.
+func (r *reloader) matchedWorkloads(ctx context.Context, namespace string, rules *reloaderv1alpha1.PolicyRules, cluster bool) ([]reloaderv1alpha1.MatchedWorkload, error) {
+	ws, err := workload.List(ctx, r.client, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []reloaderv1alpha1.MatchedWorkload
+	for _, w := range ws {
+		if !policy.MatchesTarget(rules, w) {
+			continue
+		}
+		for _, ref := range workload.References(w) {
+			src := workload.NewSource(ref.Kind)
+			if err := r.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, src); err != nil && !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			if !policy.MatchesSource(rules, ref.Kind, ref.Name, src.GetLabels()) {
+				continue
+			}
+			m := reloaderv1alpha1.MatchedWorkload{Kind: w.Kind(), Name: w.Object().GetName()}
+			if cluster {
+				m.Namespace = namespace
+			}
+			matched = append(matched, m)
+			break
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].Kind != matched[j].Kind {
+			return matched[i].Kind < matched[j].Kind
+		}
+		return matched[i].Name < matched[j].Name
+	})
+	return matched, nil
+}
+
+// capMatched returns the number of matched workloads and the first
+// maxMatchedWorkloads of them, so the status stays small in large clusters.
+func capMatched(matched []reloaderv1alpha1.MatchedWorkload) (int32, []reloaderv1alpha1.MatchedWorkload) {
+	if len(matched) > maxMatchedWorkloads {
+		return int32(len(matched)), matched[:maxMatchedWorkloads]
+	}
+	return int32(len(matched)), matched
+}
+
+// policyMatcher returns whether the rules of a policy select obj, a workload
+// as a target or a ConfigMap or Secret as a source.
+func policyMatcher(obj client.Object) (func(rules *reloaderv1alpha1.PolicyRules) bool, bool) {
+	var kind workload.SourceKind
+	switch obj.(type) {
+	case *corev1.ConfigMap:
+		kind = workload.ConfigMap
+	case *corev1.Secret:
+		kind = workload.Secret
+	default:
+		w, ok := workload.New(obj)
+		if !ok {
+			return nil, false
+		}
+		return func(rules *reloaderv1alpha1.PolicyRules) bool {
+			return policy.MatchesTarget(rules, w)
+		}, true
+	}
+	return func(rules *reloaderv1alpha1.PolicyRules) bool {
+		return policy.MatchesSource(rules, kind, obj.GetName(), obj.GetLabels())
+	}, true
+}
+
+// namespacePolicies maps a workload to the ReloadPolicies of its namespace
+// targeting it, and a ConfigMap or Secret to those selecting it.
+func (r *reloader) namespacePolicies(ctx context.Context, obj client.Object) []reconcile.Request {
+	matches, ok := policyMatcher(obj)
+	if !ok {
+		return nil
+	}
+	list := &reloaderv1alpha1.ReloadPolicyList{}
+	if err := r.client.List(ctx, list, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list reload policies")
+		return nil
+	}
+	var reqs []reconcile.Request
+	for i := range list.Items {
+		p := &list.Items[i]
+		if matches(&p.Spec.PolicyRules) {
+			reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(p)})
+		}
+	}
+	return reqs
+}
+
+// clusterPolicies maps a workload, ConfigMap or Secret to the
+// ClusterReloadPolicies selecting its namespace and it, and a namespace to
+// those selecting it.
+func (r *reloader) clusterPolicies(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+	ns, isNamespace := obj.(*corev1.Namespace)
+	matches, ok := policyMatcher(obj)
+	if !isNamespace {
+		if !ok {
+			return nil
+		}
+		ns = &corev1.Namespace{}
+		if err := r.client.Get(ctx, client.ObjectKey{Name: obj.GetNamespace()}, ns); err != nil {
+			if !apierrors.IsNotFound(err) {
+				logger.Error(err, "unable to get namespace")
+			}
+			return nil
+		}
+	}
+
+	list := &reloaderv1alpha1.ClusterReloadPolicyList{}
+	if err := r.client.List(ctx, list); err != nil {
+		logger.Error(err, "unable to list cluster reload policies")
+		return nil
+	}
+	var reqs []reconcile.Request
+	for i := range list.Items {
+		p := &list.Items[i]
+		if !policy.MatchesNamespace(&p.Spec, ns.Labels) || (!isNamespace && !matches(&p.Spec.PolicyRules)) {
+			continue
+		}
+		reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(p)})
+	}
+	return reqs
+}
+
+// enqueueMatching enqueues the requests mapped from the object of an event,
+// and for updates from the old object too, so that a policy is also
+// reconciled when an object stops matching it.
+func enqueueMatching(fn handler.MapFunc) handler.EventHandler {
+	add := func(ctx context.Context, q workqueue.RateLimitingInterface, obj client.Object) {
+		for _, req := range fn(ctx, obj) {
+			q.Add(req)
+		}
+	}
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.RateLimitingInterface) {
+			add(ctx, q, e.Object)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			add(ctx, q, e.ObjectOld)
+			add(ctx, q, e.ObjectNew)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+			add(ctx, q, e.Object)
+		},
+		GenericFunc: func(ctx context.Context, e event.GenericEvent, q workqueue.RateLimitingInterface) {
+			add(ctx, q, e.Object)
+		},
+	}
+}
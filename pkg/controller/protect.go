@@ -0,0 +1,129 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=update;patch
+// +kubebuilder:rbac:groups=core,resources=secrets/finalizers,verbs=update
+
+// inUseFinalizer blocks the deletion of a source required by a workload the
+// reloader governs, whose pods would fail to start without it.
+const inUseFinalizer = annotation.Prefix + "in-use"
+
+// protect keeps the in-use finalizer on src while live workloads governed by
+// the reloader require it, and reports the dependents blocking its deletion.
+// The finalizer is removed once no workload requires src any more, or when
+// the protection is disabled. The protection is off by default: finalizers
+// left behind by an uninstalled controller would block the deletion of the
+// sources and of their namespaces.
+func (r *reloader) protect(ctx context.Context, kind workload.SourceKind, src client.Object, workloads []workload.Workload) error {
+	var dependents []string
+	if r.store.Get().Reload.ProtectInUse {
+		for _, w := range workloads {
+			ok, err := r.requires(ctx, w, kind, src)
+			if err != nil {
+				return err
+			}
+			if ok {
+				dependents = append(dependents, w.Kind()+"/"+w.Object().GetName())
+			}
+		}
+	}
+	sort.Strings(dependents)
+
+	protected := controllerutil.ContainsFinalizer(src, inUseFinalizer)
+	deleting := src.GetDeletionTimestamp() != nil
+	if deleting && protected && len(dependents) > 0 {
+		r.recorder.Eventf(src, corev1.EventTypeWarning, "DeletionBlocked",
+			"%s %s is in use by %s, its deletion is blocked until no workload requires it",
+			kind, src.GetName(), strings.Join(dependents, ", "))
+	}
+	// 삭제 중인 object 에는 finalizer 를 새로 달 수 없음
+	want := len(dependents) > 0
+	if protected == want || (want && deleting) {
+		return nil
+	}
+
+	patch := client.MergeFromWithOptions(src.DeepCopyObject().(client.Object), client.MergeFromWithOptimisticLock{})
+	if want {
+		controllerutil.AddFinalizer(src, inUseFinalizer)
+	} else {
+		controllerutil.RemoveFinalizer(src, inUseFinalizer)
+	}
+	if err := r.client.Patch(ctx, src, patch); err != nil {
+		return err
+	}
+	log.FromContext(ctx).V(1).Info("updated in-use protection", "source", string(kind)+"/"+src.GetName(), "protected", want)
+	return nil
+}
+
+// unprotectAll removes the in-use finalizer from every source, once the
+// protection is disabled, so that none is left blocking its deletion.
+func (r *reloader) unprotectAll(ctx context.Context) error {
+	for _, list := range []client.ObjectList{&corev1.ConfigMapList{}, &corev1.SecretList{}} {
+		if err := r.client.List(ctx, list); err != nil {
+			return err
+		}
+		err := meta.EachListItem(list, func(o runtime.Object) error {
+			src := o.(client.Object)
+			if !controllerutil.ContainsFinalizer(src, inUseFinalizer) {
+				return nil
+			}
+			patch := client.MergeFromWithOptions(src.DeepCopyObject().(client.Object), client.MergeFromWithOptimisticLock{})
+			controllerutil.RemoveFinalizer(src, inUseFinalizer)
+			return client.IgnoreNotFound(r.client.Patch(ctx, src, patch))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requires reports whether w is live, governed by the reloader and cannot
+// start without src.
+func (r *reloader) requires(ctx context.Context, w workload.Workload, kind workload.SourceKind, src client.Object) (bool, error) {
+	if w.Object().GetDeletionTimestamp() != nil {
+		return false, nil
+	}
+	required := false
+	for _, ref := range workload.References(w) {
+		if ref.Kind == kind && ref.Name == src.GetName() && !ref.Optional {
+			required = true
+			break
+		}
+	}
+	if !required {
+		return false, nil
+	}
+	return r.governs(ctx, w, kind, src)
+}
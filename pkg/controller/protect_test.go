@@ -0,0 +1,120 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+)
+
+var _ = Describe("In-use protection", func() {
+	var (
+		ctx      context.Context
+		c        client.Client
+		r        *reloader
+		recorder *record.FakeRecorder
+		d        *appsv1.Deployment
+		key      = types.NamespacedName{Namespace: "default", Name: "app-config"}
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		d = &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name: "web", Namespace: "default", Annotations: map[string]string{annotation.Auto: "true"},
+		}}
+		d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "web", EnvFrom: []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}}},
+		}}}
+	})
+
+	build := func() {
+		c = newFakeClient(d,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"}},
+		)
+		recorder = record.NewFakeRecorder(10)
+		cfg := config.NewConfig()
+		cfg.Reload.ProtectInUse = true
+		r = &reloader{client: c, recorder: recorder, store: config.NewStore(cfg)}
+	}
+	getSource := func() *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{}
+		Expect(c.Get(ctx, key, cm)).To(Succeed())
+		return cm
+	}
+
+	It("blocks the deletion of sources required by governed workloads", func() {
+		build()
+		_, err := r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(getSource().Finalizers).To(ConsistOf(inUseFinalizer))
+
+		Expect(c.Delete(ctx, getSource())).To(Succeed())
+		_, err = r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(getSource().DeletionTimestamp).NotTo(BeNil())
+		Eventually(recorder.Events).Should(Receive(ContainSubstring("DeletionBlocked ConfigMap app-config is in use by Deployment/web")))
+
+		Expect(c.Delete(ctx, d)).To(Succeed())
+		_, err = r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apierrors.IsNotFound(c.Get(ctx, key, &corev1.ConfigMap{}))).To(BeTrue())
+	})
+
+	It("leaves sources of workloads tolerating their absence alone", func() {
+		optional := true
+		d.Spec.Template.Spec.Containers[0].EnvFrom[0].ConfigMapRef.Optional = &optional
+		build()
+		_, err := r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(getSource().Finalizers).To(BeEmpty())
+	})
+
+	It("removes the finalizer when the protection is disabled", func() {
+		build()
+		_, err := r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+
+		r.store.Get().Reload.ProtectInUse = false
+		_, err = r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(getSource().Finalizers).To(BeEmpty())
+	})
+
+	It("releases every source once the protection is turned off", func() {
+		build()
+		_, err := r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(getSource().Finalizers).To(ConsistOf(inUseFinalizer))
+
+		Expect(r.unprotectAll(ctx)).To(Succeed())
+		Expect(getSource().Finalizers).To(BeEmpty())
+	})
+})
@@ -0,0 +1,387 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	reloaderv1alpha1 "github.com/hotkimho/reloader-server/project/api/v1alpha1"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/strategy"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// +kubebuilder:rbac:groups=reloader.accordions.edu,resources=reloadrecords,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=reloader.accordions.edu,resources=reloadrecords/status,verbs=get;update;patch
+
+// observedSource is the content of a source last seen by the controller.
+type observedSource struct {
+	hash string
+	keys map[string]string
+}
+
+// sourceChange is a change of a source observed by the controller.
+type sourceChange struct {
+	oldHash, newHash string
+	changedKeys      []string
+	// oldKeys are the key hashes of the previous content.
+	oldKeys map[string]string
+}
+
+// observeSource returns the current content of the source and how it
+// changed since it was last observed. The change is nil when the content is
+// the same or the source was never observed, e.g. before it was first
+// reconciled. workloads are the workloads referencing the source.
+func (r *reloader) observeSource(ctx context.Context, kind workload.SourceKind, key types.NamespacedName, workloads []workload.Workload) (observedSource, *sourceChange, error) {
+	hashes, err := workload.SourceHashes(ctx, r.client, workload.Reference{Kind: kind, Name: key.Name}, key.Namespace)
+	if err != nil {
+		return observedSource{}, nil, err
+	}
+	current := observedSource{hash: workload.ContentHash(hashes), keys: hashes}
+
+	prev, ok := r.lastObserved(kind, key, workloads)
+	if !ok || prev.hash == current.hash {
+		return current, nil, nil
+	}
+	return current, &sourceChange{
+		oldHash:     prev.hash,
+		newHash:     current.hash,
+		changedKeys: workload.ChangedKeys(prev.keys, current.keys),
+		oldKeys:     prev.keys,
+	}, nil
+}
+
+// lastObserved returns the content of the source last observed, from memory
+// or, after a restart of the controller, from the annotation of a workload
+// consuming it.
+func (r *reloader) lastObserved(kind workload.SourceKind, key types.NamespacedName, workloads []workload.Workload) (observedSource, bool) {
+	if v, ok := r.observed.Load(sourceID(kind, key)); ok {
+		return v.(observedSource), true
+	}
+	ref := workload.Reference{Kind: kind, Name: key.Name}.String()
+	for _, w := range workloads {
+		if keys, ok := observedSources(w.Object())[ref]; ok {
+			return observedSource{hash: workload.ContentHash(keys), keys: keys}, true
+		}
+	}
+	return observedSource{}, false
+}
+
+// storeObserved remembers current as the content of the source last
+// observed. It is persisted on the governed workloads, which are the ones
+// reloaded or recovering image pulls for its changes, and forgotten once the
+// source is deleted.
+func (r *reloader) storeObserved(ctx context.Context, kind workload.SourceKind, key types.NamespacedName,
+	current observedSource, deleted bool, workloads, governed []workload.Workload) error {
+	id := sourceID(kind, key)
+	ref := workload.Reference{Kind: kind, Name: key.Name}.String()
+	if deleted {
+		r.observed.Delete(id)
+		for _, w := range workloads {
+			if err := r.setObservedSource(ctx, w, ref, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	r.observed.Store(id, current)
+	for _, w := range governed {
+		if err := r.setObservedSource(ctx, w, ref, current.keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setObservedSource records keys as the observed content of the source ref
+// on w, or removes it when keys is nil. Sources w no longer consumes or
+// pulls images with are dropped on the way.
+func (r *reloader) setObservedSource(ctx context.Context, w workload.Workload, ref string, keys map[string]string) error {
+	obj := w.Object()
+	observed := observedSources(obj)
+	pullSecrets, err := workload.PullSecrets(ctx, r.client, w)
+	if err != nil {
+		return err
+	}
+	referenced := map[string]bool{}
+	for _, src := range append(workload.References(w), pullSecrets...) {
+		referenced[src.String()] = true
+	}
+	updated := map[string]map[string]string{}
+	for k, v := range observed {
+		if referenced[k] && k != ref {
+			updated[k] = v
+		}
+	}
+	if keys != nil {
+		updated[ref] = keys
+	}
+	if equality.Semantic.DeepEqual(observed, updated) {
+		return nil
+	}
+
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	if len(updated) == 0 {
+		removeAnnotation(obj, annotation.ObservedSources)
+	} else {
+		data, err := json.Marshal(updated)
+		if err != nil {
+			return err
+		}
+		setAnnotation(obj, annotation.ObservedSources, string(data))
+	}
+	return client.IgnoreNotFound(r.client.Patch(ctx, obj, patch))
+}
+
+// observedSources returns the observed sources annotation of obj.
+func observedSources(obj client.Object) map[string]map[string]string {
+	observed := map[string]map[string]string{}
+	if v, ok := obj.GetAnnotations()[annotation.ObservedSources]; ok {
+		_ = json.Unmarshal([]byte(v), &observed)
+	}
+	return observed
+}
+
+func sourceID(kind workload.SourceKind, key types.NamespacedName) string {
+	return string(kind) + "/" + key.String()
+}
+
+// reloadTarget returns the record target of w when the change of a source
+// reloads it, that is when its dependency hash differs from the last one it
+// was reloaded with.
+func (r *reloader) reloadTarget(ctx context.Context, w workload.Workload) (reloaderv1alpha1.ReloadTarget, bool, error) {
+	obj := w.Object()
+	hash, err := workload.Hash(ctx, r.client, w)
+	if err != nil || obj.GetAnnotations()[annotation.ConfigHash] == hash {
+		return reloaderv1alpha1.ReloadTarget{}, false, err
+	}
+
+	name := strategy.Restart
+	if w.Kind() != workload.CronJob {
+		s, err := r.policies(ctx, w)
+		if err != nil {
+			return reloaderv1alpha1.ReloadTarget{}, false, err
+		}
+		name = strategy.Name(s.Annotations(obj.GetAnnotations()))
+	}
+	return reloaderv1alpha1.ReloadTarget{Kind: w.Kind(), Name: obj.GetName(), Hash: hash, Strategy: name}, true, nil
+}
+
+// createRecord records the change of src and the workloads it reloads.
+func (r *reloader) createRecord(ctx context.Context, kind workload.SourceKind, src client.Object, key types.NamespacedName,
+	change *sourceChange, targets []reloaderv1alpha1.ReloadTarget, now time.Time) error {
+	if !r.store.Get().Records.Enabled {
+		return nil
+	}
+	record := &reloaderv1alpha1.ReloadRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: strings.ToLower(string(kind)) + "-" + key.Name + "-",
+			Namespace:    key.Namespace,
+		},
+		Spec: reloaderv1alpha1.ReloadRecordSpec{
+			Source:       reloaderv1alpha1.SourceReference{Kind: string(kind), Name: key.Name},
+			OldHash:      change.oldHash,
+			NewHash:      change.newHash,
+			ChangedKeys:  change.changedKeys,
+			FieldManager: fieldManager(src, change.changedKeys),
+			Targets:      targets,
+			Timestamp:    metav1.NewTime(now.UTC().Truncate(time.Second)),
+		},
+	}
+	if err := r.client.Create(ctx, record); err != nil {
+		if meta.IsNoMatchError(err) {
+			log.FromContext(ctx).V(1).Info("ReloadRecord CRD is not installed, skipping record")
+			return nil
+		}
+		return err
+	}
+	log.FromContext(ctx).Info("recorded reload", "record", record.Name, "targets", len(targets))
+	return nil
+}
+
+// fieldManager returns the field manager responsible for the changed keys
+// of src, or "" when unknown.
+func fieldManager(src client.Object, keys []string) string {
+	attribution, _ := workload.Attribute(src, keys)
+	return attribution.Manager
+}
+
+// ReloadRecordReconciler tracks the rollout of the targets of a ReloadRecord
+// and deletes it once the retention elapsed
+type ReloadRecordReconciler struct {
+	*reloader
+}
+
+func (r *ReloadRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	record := &reloaderv1alpha1.ReloadRecord{}
+	if err := r.client.Get(ctx, req.NamespacedName, record); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	now := time.Now()
+	expiry := record.Spec.Timestamp.Add(r.store.Get().Records.Retention.Duration)
+	if !now.Before(expiry) {
+		log.FromContext(ctx).V(1).Info("deleting expired reload record", "record", record.Name)
+		return ctrl.Result{}, client.IgnoreNotFound(r.client.Delete(ctx, record))
+	}
+	result := ctrl.Result{RequeueAfter: expiry.Sub(now)}
+	if record.Status.Phase.Done() {
+		return result, nil
+	}
+
+	status, err := r.recordStatus(ctx, record, now)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if equality.Semantic.DeepEqual(record.Status, status) {
+		return result, nil
+	}
+	record.Status = status
+	return result, r.client.Status().Update(ctx, record)
+}
+
+// recordStatus summarizes the progress of the targets of record.
+func (r *reloader) recordStatus(ctx context.Context, record *reloaderv1alpha1.ReloadRecord, now time.Time) (reloaderv1alpha1.ReloadRecordStatus, error) {
+	status := reloaderv1alpha1.ReloadRecordStatus{CompletionTime: record.Status.CompletionTime}
+	counts := map[reloaderv1alpha1.ReloadPhase]int{}
+	for _, t := range record.Spec.Targets {
+		ts, err := r.targetStatus(ctx, record.Namespace, t)
+		if err != nil {
+			return status, err
+		}
+		counts[ts.Phase]++
+		status.Targets = append(status.Targets, ts)
+	}
+
+	switch {
+	case counts[reloaderv1alpha1.ReloadFailed] > 0:
+		status.Phase = reloaderv1alpha1.ReloadFailed
+	case counts[reloaderv1alpha1.ReloadRolledBack] > 0:
+		status.Phase = reloaderv1alpha1.ReloadRolledBack
+	case counts[reloaderv1alpha1.ReloadSucceeded] == len(record.Spec.Targets):
+		status.Phase = reloaderv1alpha1.ReloadSucceeded
+	case counts[reloaderv1alpha1.ReloadPending] == len(record.Spec.Targets):
+		status.Phase = reloaderv1alpha1.ReloadPending
+	default:
+		status.Phase = reloaderv1alpha1.ReloadInProgress
+	}
+	if status.Phase.Done() && status.CompletionTime == nil {
+		t := metav1.NewTime(now.UTC().Truncate(time.Second))
+		status.CompletionTime = &t
+	}
+	return status, nil
+}
+
+// targetStatus returns the progress of the reload of t. A target whose
+// sources changed again counts as reloaded once it caught up with them.
+func (r *reloader) targetStatus(ctx context.Context, namespace string, t reloaderv1alpha1.ReloadTarget) (reloaderv1alpha1.TargetStatus, error) {
+	status := reloaderv1alpha1.TargetStatus{Kind: t.Kind, Name: t.Name}
+	phase := func(p reloaderv1alpha1.ReloadPhase, message string) (reloaderv1alpha1.TargetStatus, error) {
+		status.Phase, status.Message = p, message
+		return status, nil
+	}
+
+	obj, ok := workload.NewObject(t.Kind)
+	if !ok {
+		return phase(reloaderv1alpha1.ReloadFailed, "workload kind is no longer supported")
+	}
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: t.Name}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return phase(reloaderv1alpha1.ReloadFailed, "workload was deleted")
+		}
+		return status, err
+	}
+	w, _ := workload.New(obj)
+	annotations := obj.GetAnnotations()
+	if reason, ok := annotations[annotation.Hold]; ok {
+		return phase(reloaderv1alpha1.ReloadFailed, "reloads are on hold: "+reason)
+	}
+
+	applied := annotations[annotation.ConfigHash]
+	if applied != t.Hash {
+		current, err := workload.Hash(ctx, r.client, w)
+		if err != nil {
+			return status, err
+		}
+		if current == t.Hash || applied != current {
+			if _, ok := annotations[annotation.SyncingReload]; ok {
+				return phase(reloaderv1alpha1.ReloadInProgress, "reloading pods in place")
+			}
+			if pending, ok := getPendingReload(obj); ok {
+				return phase(reloaderv1alpha1.ReloadPending, pending.Reason)
+			}
+			return phase(reloaderv1alpha1.ReloadPending, "")
+		}
+	}
+
+	if _, ok := annotations[annotation.DeferredReload]; ok {
+		return phase(reloaderv1alpha1.ReloadInProgress, "deferred until the workload runs pods again")
+	}
+	// 무시한 변경은 template 을 그대로 두고 applied 만 바꾸므로 target 의 hash 도 허용
+	if template := w.TemplateAnnotation(annotation.ConfigHash); t.Strategy == strategy.Restart && template != "" && template != applied && template != t.Hash {
+		return phase(reloaderv1alpha1.ReloadRolledBack, "pod template was rolled back to a previous configuration")
+	}
+	done, failure := workload.RolloutStatus(w)
+	switch {
+	case failure != "":
+		return phase(reloaderv1alpha1.ReloadFailed, failure)
+	case !done:
+		return phase(reloaderv1alpha1.ReloadInProgress, "rolling out")
+	}
+	return phase(reloaderv1alpha1.ReloadSucceeded, "")
+}
+
+// workloadRecords maps a workload to the unfinished ReloadRecords targeting it.
+func (r *reloader) workloadRecords(ctx context.Context, obj client.Object) []reconcile.Request {
+	w, ok := workload.New(obj)
+	if !ok {
+		return nil
+	}
+	list := &reloaderv1alpha1.ReloadRecordList{}
+	if err := r.client.List(ctx, list, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list reload records")
+		return nil
+	}
+
+	var reqs []reconcile.Request
+	for i := range list.Items {
+		record := &list.Items[i]
+		if record.Status.Phase.Done() {
+			continue
+		}
+		for _, t := range record.Spec.Targets {
+			if t.Kind == w.Kind() && t.Name == obj.GetName() {
+				reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(record)})
+				break
+			}
+		}
+	}
+	return reqs
+}
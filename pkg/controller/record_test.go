@@ -0,0 +1,214 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	reloaderv1alpha1 "github.com/hotkimho/reloader-server/project/api/v1alpha1"
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+)
+
+var _ = Describe("Reload records", func() {
+	var (
+		ctx context.Context
+		r   *reloader
+		d   *appsv1.Deployment
+		cm  *corev1.ConfigMap
+		key = types.NamespacedName{Namespace: "default", Name: "web"}
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		d = &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name: "web", Namespace: "default", Annotations: map[string]string{annotation.Auto: "true"},
+		}}
+		d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "web", EnvFrom: []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web"}}},
+		}}}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Data:       map[string]string{"LOG_LEVEL": "info", "DB_URL": "postgres://a"},
+		}
+	})
+
+	build := func(objs ...client.Object) {
+		c := newFakeClient(append([]client.Object{d, cm, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}}, objs...)...)
+		r = &reloader{client: c, recorder: record.NewFakeRecorder(10), store: config.NewStore(config.NewConfig())}
+	}
+	records := func() []reloaderv1alpha1.ReloadRecord {
+		list := &reloaderv1alpha1.ReloadRecordList{}
+		Expect(r.client.List(ctx, list)).To(Succeed())
+		return list.Items
+	}
+	getDeployment := func() *appsv1.Deployment {
+		got := &appsv1.Deployment{}
+		Expect(r.client.Get(ctx, key, got)).To(Succeed())
+		return got
+	}
+
+	It("records observed changes with their keys, field manager and targets", func() {
+		build()
+		_, err := r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		// 처음 본 source 는 이전 내용을 모르므로 기록하지 않음
+		Expect(records()).To(BeEmpty())
+
+		Expect(r.client.Get(ctx, key, cm)).To(Succeed())
+		cm.Data["LOG_LEVEL"] = "debug"
+		now := metav1.Now()
+		cm.ManagedFields = []metav1.ManagedFieldsEntry{
+			{Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate, Time: &metav1.Time{Time: now.Add(-time.Hour)},
+				FieldsType: "FieldsV1", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:LOG_LEVEL":{}}}`)}},
+			// data 를 건드리지 않은 최근 변경은 제외
+			{Manager: "kubectl-label", Operation: metav1.ManagedFieldsOperationUpdate, Time: &now,
+				FieldsType: "FieldsV1", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:team":{}}}}`)}},
+		}
+		Expect(r.client.Update(ctx, cm)).To(Succeed())
+		r.store.Get().Reload.Cooldown = metav1.Duration{}
+		_, err = r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+
+		items := records()
+		Expect(items).To(HaveLen(1))
+		spec := items[0].Spec
+		Expect(spec.Source).To(Equal(reloaderv1alpha1.SourceReference{Kind: "ConfigMap", Name: "web"}))
+		Expect(spec.OldHash).NotTo(BeEmpty())
+		Expect(spec.NewHash).NotTo(Equal(spec.OldHash))
+		Expect(spec.ChangedKeys).To(Equal([]string{"LOG_LEVEL"}))
+		Expect(spec.FieldManager).To(Equal("kubectl-edit"))
+		Eventually(r.recorder.(*record.FakeRecorder).Events).Should(Receive(ContainSubstring("ConfigMap/web changed by kubectl-edit")))
+		Expect(spec.Targets).To(HaveLen(1))
+		Expect(spec.Targets[0].Kind).To(Equal("Deployment"))
+		Expect(spec.Targets[0].Strategy).To(Equal("restart"))
+
+		// 내용이 그대로면 다시 기록하지 않음
+		_, err = r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records()).To(HaveLen(1))
+	})
+
+	It("records changes made while the controller was down", func() {
+		build()
+		_, err := r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		// source 가 아닌 workload 에 기록
+		Expect(r.client.Get(ctx, key, cm)).To(Succeed())
+		Expect(cm.Annotations).To(BeEmpty())
+		Expect(getDeployment().Annotations).To(HaveKey(annotation.ObservedSources))
+
+		cm.Data["LOG_LEVEL"] = "debug"
+		Expect(r.client.Update(ctx, cm)).To(Succeed())
+		cfg := config.NewConfig()
+		cfg.Reload.Cooldown = metav1.Duration{}
+		r = &reloader{client: r.client, recorder: record.NewFakeRecorder(10), store: config.NewStore(cfg)}
+		_, err = r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+
+		items := records()
+		Expect(items).To(HaveLen(1))
+		Expect(items[0].Spec.OldHash).NotTo(BeEmpty())
+		Expect(items[0].Spec.ChangedKeys).To(Equal([]string{"LOG_LEVEL"}))
+
+		// 삭제된 source 는 더 이상 기억하지 않음
+		Expect(r.client.Delete(ctx, cm)).To(Succeed())
+		_, err = r.reconcileSource(ctx, "ConfigMap", key)
+		Expect(err).NotTo(HaveOccurred())
+		_, ok := r.observed.Load(sourceID("ConfigMap", key))
+		Expect(ok).To(BeFalse())
+		Expect(getDeployment().Annotations).NotTo(HaveKey(annotation.ObservedSources))
+	})
+
+	Describe("status", func() {
+		newRecord := func(hash string, age time.Duration) *reloaderv1alpha1.ReloadRecord {
+			return &reloaderv1alpha1.ReloadRecord{
+				ObjectMeta: metav1.ObjectMeta{Name: "configmap-web-abcde", Namespace: "default"},
+				Spec: reloaderv1alpha1.ReloadRecordSpec{
+					Source:    reloaderv1alpha1.SourceReference{Kind: "ConfigMap", Name: "web"},
+					Targets:   []reloaderv1alpha1.ReloadTarget{{Kind: "Deployment", Name: "web", Hash: hash, Strategy: "restart"}},
+					Timestamp: metav1.NewTime(time.Now().Add(-age)),
+				},
+			}
+		}
+		reconcile := func() *reloaderv1alpha1.ReloadRecord {
+			rr := &ReloadRecordReconciler{r}
+			_, err := rr.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "configmap-web-abcde"}})
+			Expect(err).NotTo(HaveOccurred())
+			got := &reloaderv1alpha1.ReloadRecord{}
+			if err := r.client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "configmap-web-abcde"}, got); apierrors.IsNotFound(err) {
+				return nil
+			}
+			return got
+		}
+
+		It("follows the target from pending to rolled out", func() {
+			d.Status.ObservedGeneration = 1
+			build(newRecord("h1", time.Minute))
+			got := reconcile()
+			Expect(got.Status.Phase).To(Equal(reloaderv1alpha1.ReloadPending))
+
+			d := getDeployment()
+			d.Annotations[annotation.ConfigHash] = "h1"
+			d.Spec.Template.Annotations = map[string]string{annotation.ConfigHash: "h1"}
+			Expect(r.client.Update(ctx, d)).To(Succeed())
+			got = reconcile()
+			Expect(got.Status.Phase).To(Equal(reloaderv1alpha1.ReloadInProgress))
+			Expect(got.Status.Targets[0].Message).To(Equal("rolling out"))
+
+			d = getDeployment()
+			d.Status = appsv1.DeploymentStatus{ObservedGeneration: d.Generation, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}
+			Expect(r.client.Status().Update(ctx, d)).To(Succeed())
+			got = reconcile()
+			Expect(got.Status.Phase).To(Equal(reloaderv1alpha1.ReloadSucceeded))
+			Expect(got.Status.CompletionTime).NotTo(BeNil())
+		})
+
+		It("detects rolled back pod templates", func() {
+			d.Annotations[annotation.ConfigHash] = "h1"
+			d.Spec.Template.Annotations = map[string]string{annotation.ConfigHash: "h0"}
+			build(newRecord("h1", time.Minute))
+			Expect(reconcile().Status.Phase).To(Equal(reloaderv1alpha1.ReloadRolledBack))
+		})
+
+		It("fails when a target is deleted", func() {
+			build(newRecord("h1", time.Minute))
+			Expect(r.client.Delete(ctx, d)).To(Succeed())
+			got := reconcile()
+			Expect(got.Status.Phase).To(Equal(reloaderv1alpha1.ReloadFailed))
+			Expect(got.Status.Targets[0].Message).To(Equal("workload was deleted"))
+		})
+
+		It("deletes records past the retention", func() {
+			build(newRecord("h1", 8*24*time.Hour))
+			Expect(reconcile()).To(BeNil())
+		})
+	})
+})
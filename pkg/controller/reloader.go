@@ -0,0 +1,368 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	reloaderv1alpha1 "github.com/hotkimho/reloader-server/project/api/v1alpha1"
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/approval"
+	"github.com/hotkimho/reloader-server/project/pkg/strategy"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// reloader holds the state shared by the ConfigMap and Secret reconcilers.
+type reloader struct {
+	client   client.Client
+	recorder record.EventRecorder
+	store    *config.Store
+	executor strategy.Executor
+
+	// observed holds the content of every source last seen, to record how
+	// it changed. It is also persisted on the sources workloads reload for.
+	observed sync.Map
+}
+
+func newReloader(mgr ctrl.Manager, store *config.Store) (*reloader, error) {
+	executor, err := strategy.NewExecutor(mgr.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	return &reloader{
+		client:   mgr.GetClient(),
+		recorder: mgr.GetEventRecorderFor("reloader-server"),
+		store:    store,
+		executor: executor,
+	}, nil
+}
+
+// reconcileSource reloads every workload consuming the given source, opted in
+// by annotation or selected by a policy together with the source, whose
+// dependency hash no longer matches the one it was last reloaded with.
+func (r *reloader) reconcileSource(ctx context.Context, kind workload.SourceKind, key types.NamespacedName) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithValues("kind", kind)
+
+	deleted := false
+	src := workload.NewSource(kind)
+	if err := r.client.Get(ctx, key, src); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		deleted = true
+		src.SetName(key.Name)
+	}
+	ref := workload.Reference{Kind: kind, Name: key.Name}
+	workloads, err := workload.List(ctx, r.client, key.Namespace, client.MatchingFields{workload.SourceIndex: ref.String()})
+	if err != nil {
+		logger.Error(err, "unable to list workloads")
+		return ctrl.Result{}, err
+	}
+	// registry credential 로 쓰는 워크로드는 source index 에 없으므로 따로 조회
+	var pulling []workload.Workload
+	if kind == workload.Secret {
+		if pulling, err = r.pullingWorkloads(ctx, key, src); err != nil {
+			logger.Error(err, "unable to list workloads pulling images")
+			return ctrl.Result{}, err
+		}
+	}
+	current, change, err := r.observeSource(ctx, kind, key, append(workloads, pulling...))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	cause := fmt.Sprintf("%s/%s changed", kind, key.Name)
+	ignored := ""
+	var attribution workload.Attribution
+	switch {
+	case deleted:
+		cause = fmt.Sprintf("%s/%s was deleted", kind, key.Name)
+	case change != nil:
+		var ok bool
+		attribution, ok = workload.Attribute(src, change.changedKeys)
+		manager := "unknown"
+		if ok {
+			manager = r.managerLabel(attribution.Manager)
+			cause += " by " + attribution.Manager
+		}
+		sourceChangesTotal.WithLabelValues(key.Namespace, string(kind), manager).Inc()
+		logger.Info("source changed", "source", key.Name, "keys", change.changedKeys,
+			"changedBy", attribution.Manager, "operation", attribution.Operation, "changedAt", attribution.Time)
+		if reason, ok := r.ignoredChange(src, attribution, ok); ok {
+			ignored = reason
+		}
+	}
+
+	var review time.Duration
+	if !deleted {
+		if err := r.protect(ctx, kind, src, workloads); err != nil {
+			logger.Error(err, "unable to update in-use protection")
+			return ctrl.Result{}, err
+		}
+		// 삭제 중인 source 는 finalizer 가 빠진 뒤 NotFound 로 처리
+		if src.GetDeletionTimestamp() != nil {
+			return ctrl.Result{}, nil
+		}
+		// 무시하는 변경도 critical source 면 승인을 받아야 함
+		if ignored == "" || approval.Critical(src) {
+			if review, err = r.reviewChange(ctx, kind, src, current.hash, attribution, time.Now()); err != nil {
+				logger.Error(err, "unable to review change")
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	var (
+		governed []workload.Workload
+		targets  []reloaderv1alpha1.ReloadTarget
+	)
+	for _, w := range workloads {
+		if !workload.DependsOn(w, kind, key.Name) {
+			continue
+		}
+		ok, err := r.governs(ctx, w, kind, src)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !ok {
+			continue
+		}
+		if deleted && r.ignoreDelete(w, kind, key.Name) {
+			logger.V(1).Info("ignoring deleted source", "workload", w.Kind()+"/"+w.Object().GetName())
+			continue
+		}
+		governed = append(governed, w)
+
+		if change == nil || ignored != "" {
+			continue
+		}
+		target, reloads, err := r.reloadTarget(ctx, w)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if reloads {
+			targets = append(targets, target)
+		}
+	}
+	if ignored != "" {
+		logger.Info("ignoring source change", "source", key.Name, "reason", ignored)
+		if err := r.absorbChange(ctx, kind, src, change, governed); err != nil {
+			logger.Error(err, "unable to absorb ignored change")
+			return ctrl.Result{}, err
+		}
+		if err := r.storeObserved(ctx, kind, key, current, deleted, append(workloads, pulling...), append(governed, pulling...)); err != nil {
+			logger.Error(err, "unable to store observed content")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+	// record 를 만든 뒤에 내용을 갱신해야 실패해도 다음 reconcile 에서 다시 기록됨
+	if change != nil && len(targets) > 0 {
+		if err := r.createRecord(ctx, kind, src, key, change, targets, time.Now()); err != nil {
+			logger.Error(err, "unable to record reload")
+			return ctrl.Result{}, err
+		}
+	}
+	// registry credential 이 갱신되면 image pull 에 실패한 pod 만 재생성
+	if secret, ok := src.(*corev1.Secret); ok && change != nil && !deleted {
+		if err := r.recoverImagePulls(ctx, secret, pulling); err != nil {
+			logger.Error(err, "unable to recover pods failing image pulls")
+			return ctrl.Result{}, err
+		}
+	}
+	if err := r.storeObserved(ctx, kind, key, current, deleted, append(workloads, pulling...), append(governed, pulling...)); err != nil {
+		logger.Error(err, "unable to store observed content")
+		return ctrl.Result{}, err
+	}
+
+	result := ctrl.Result{RequeueAfter: review}
+	for _, w := range governed {
+		wait, err := r.reconcileWorkload(ctx, w, cause)
+		if err != nil {
+			logger.Error(err, "unable to reload workload", "workload", w.Kind()+"/"+w.Object().GetName())
+			return ctrl.Result{}, err
+		}
+		result.RequeueAfter = minRequeue(result.RequeueAfter, wait)
+	}
+	return result, nil
+}
+
+// reconcileWorkload reloads w if its dependency hash changed. A positive
+// duration asks the caller to come back later, e.g. when w is cooling down.
+// The cause is reported on the Event of the reload.
+func (r *reloader) reconcileWorkload(ctx context.Context, w workload.Workload, cause string) (time.Duration, error) {
+	hash, err := workload.Hash(ctx, r.client, w)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	if w.Object().GetAnnotations()[annotation.ConfigHash] == hash {
+		if _, ok := w.Object().GetAnnotations()[annotation.DeferredReload]; ok {
+			return r.confirmDeferred(ctx, w, hash, now)
+		}
+		return 0, r.dropPendingReload(ctx, w)
+	}
+
+	s, err := r.policies(ctx, w)
+	if err != nil {
+		return 0, err
+	}
+	if wait, err := r.debounce(ctx, w, s, hash, now); err != nil || wait > 0 {
+		return wait, err
+	}
+
+	decision := r.admit(w, s, now)
+	if decision.held {
+		log.FromContext(ctx).V(1).Info("workload is on hold, skipping reload", "workload", w.Object().GetName())
+		return 0, nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := r.client.Get(ctx, types.NamespacedName{Name: w.Object().GetNamespace()}, ns); err != nil {
+		return 0, err
+	}
+	if r.paused(ns) {
+		return 0, r.queue(ctx, w, hash, reasonPaused, time.Time{})
+	}
+	if r.awaitingApproval(w) {
+		return 0, r.queue(ctx, w, hash, reasonAwaitingApproval, time.Time{})
+	}
+	source, unapproved, err := r.unapprovedSource(ctx, w)
+	if err != nil {
+		return 0, err
+	}
+	if unapproved {
+		return 0, r.queue(ctx, w, hash, reasonSourceApproval(source), time.Time{})
+	}
+
+	schedule, err := r.schedule(ns, s)
+	if err != nil {
+		return 0, err
+	}
+	if !schedule.Allowed(now) {
+		next := schedule.Next(now)
+		if next.IsZero() {
+			return 0, r.queue(ctx, w, hash, "no upcoming maintenance window", next)
+		}
+		return next.Sub(now), r.queue(ctx, w, hash, "outside maintenance window", next)
+	}
+
+	switch {
+	case decision.hold != "":
+		return 0, r.hold(ctx, w, decision.hold)
+	case decision.wait > 0:
+		return decision.wait, nil
+	}
+
+	return r.apply(ctx, w, s, hash, cause, now)
+}
+
+// restart rolls the pods of w by stamping hash on its pod template.
+func (r *reloader) restart(ctx context.Context, w workload.Workload, hash, cause string, now time.Time) error {
+	return r.commit(ctx, w, hash, strategy.Restart, now, func() {
+		w.SetTemplateAnnotation(annotation.ConfigHash, hash)
+		// hash 가 그대로인 scheduled restart 에도 pod 가 교체되도록
+		w.SetTemplateAnnotation(annotation.RestartedAt, now.UTC().Format(time.RFC3339))
+	}, "Restarted pods to pick up configuration changes: "+cause)
+}
+
+// commit records that w was reloaded to hash, after applying mutate to it.
+func (r *reloader) commit(ctx context.Context, w workload.Workload, hash, strategyName string, now time.Time, mutate func(), message string) error {
+	obj := w.Object()
+	static, err := workload.StaticHash(ctx, r.client, w)
+	if err != nil {
+		return err
+	}
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+
+	if mutate != nil {
+		mutate()
+	}
+	setAnnotation(obj, annotation.ConfigHash, hash)
+	setAnnotation(obj, annotation.StaticHash, static)
+	setAnnotation(obj, annotation.ReloadHistory, recordReload(obj, now, r.store.Get().Reload.FlapWindow.Duration))
+	removeAnnotation(obj, annotation.PendingReload)
+	removeAnnotation(obj, annotation.Approve)
+	removeAnnotation(obj, annotation.SyncingReload)
+	removeAnnotation(obj, annotation.DeferredReload)
+	removeAnnotation(obj, annotation.DeferredWaiting)
+
+	if err := r.client.Patch(ctx, obj, patch); err != nil {
+		return err
+	}
+
+	reloadsTotal.WithLabelValues(obj.GetNamespace(), w.Kind(), obj.GetName(), strategyName).Inc()
+	r.recorder.Event(obj, corev1.EventTypeNormal, "Reloaded", message)
+	log.FromContext(ctx).Info("reloaded workload", "workload", w.Kind()+"/"+obj.GetName(), "hash", hash, "strategy", strategyName)
+	return nil
+}
+
+func optedIn(w workload.Workload) bool {
+	return w.Object().GetAnnotations()[annotation.Auto] == "true"
+}
+
+// ignoreDelete reports whether the deletion of the source of the given kind
+// and name should not reload w. Pods cannot start without a required source,
+// so its deletion never reloads w. The deletion of an optional source reloads
+// w unless the workload annotation, or failing that the controller config,
+// opts out.
+func (r *reloader) ignoreDelete(w workload.Workload, kind workload.SourceKind, name string) bool {
+	for _, ref := range workload.References(w) {
+		if ref.Kind == kind && ref.Name == name && !ref.Optional {
+			return true
+		}
+	}
+	if v, ok := w.Object().GetAnnotations()[annotation.IgnoreDelete]; ok {
+		return v == "true"
+	}
+	return r.store.Get().Reload.IgnoreDelete
+}
+
+func setAnnotation(obj client.Object, key, value string) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[key] = value
+	obj.SetAnnotations(annotations)
+}
+
+func removeAnnotation(obj client.Object, key string) {
+	annotations := obj.GetAnnotations()
+	delete(annotations, key)
+	obj.SetAnnotations(annotations)
+}
+
+// minRequeue returns the shortest positive duration of a and b.
+func minRequeue(a, b time.Duration) time.Duration {
+	if a <= 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
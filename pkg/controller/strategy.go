@@ -0,0 +1,268 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/policy"
+	"github.com/hotkimho/reloader-server/project/pkg/strategy"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=pods/exec,verbs=create;get
+
+// retryInterval is how often pods whose files are not synced yet are retried.
+const retryInterval = 10 * time.Second
+
+// syncingReload is the value of the syncing reload annotation.
+type syncingReload struct {
+	Hash  string    `json:"hash"`
+	Since time.Time `json:"since"`
+	// Reloaded are the pods already reloaded to Hash.
+	Reloaded []string `json:"reloaded,omitempty"`
+}
+
+// apply reloads w to hash with the strategy selected by its annotations or,
+// failing that, by the policies selecting it.
+// An invalid strategy falls back to a restart. CronJobs only get their job
+// template updated, and dormant workloads only get hash recorded.
+func (r *reloader) apply(ctx context.Context, w workload.Workload, s *policy.Settings, hash, cause string, now time.Time) (time.Duration, error) {
+	obj := w.Object()
+	if w.Kind() == workload.CronJob {
+		return 0, r.updateJobTemplate(ctx, w, hash, cause, now)
+	}
+	if reason, dormant := workload.Dormant(w); dormant {
+		return 0, r.deferReload(ctx, w, hash, cause, reason)
+	}
+
+	annotations := s.Annotations(obj.GetAnnotations())
+	name := strategy.Name(annotations)
+	opts := strategy.Options{
+		Timeout:  r.store.Get().Strategy.Timeout.Duration,
+		Executor: r.executor,
+	}
+	if name == strategy.Exec || name == strategy.Signal {
+		files, err := r.mountedFiles(ctx, w)
+		if err != nil {
+			return 0, err
+		}
+		opts.Files = files
+	}
+
+	pr, err := strategy.New(annotations, opts)
+	if err != nil {
+		r.recorder.Eventf(obj, corev1.EventTypeWarning, "InvalidStrategy", "%v, restarting instead", err)
+		return 0, r.restart(ctx, w, hash, cause, now)
+	}
+	if pr == nil {
+		return 0, r.restart(ctx, w, hash, cause, now)
+	}
+
+	// env, subPath 로 소비되는 key 는 실행 중인 pod 에 반영되지 않으므로 restart
+	// annotation 이 없으면 (이전 버전에서 reload 된 workload) 바뀐 key 를 알 수 없으므로 restart
+	static, err := workload.StaticHash(ctx, r.client, w)
+	if err != nil {
+		return 0, err
+	}
+	if prev, ok := obj.GetAnnotations()[annotation.StaticHash]; !ok || prev != static {
+		r.recorder.Eventf(obj, corev1.EventTypeNormal, "RestartRequired",
+			"Changed keys are consumed through env or subPath, restarting instead of the %s strategy", name)
+		return 0, r.restart(ctx, w, hash, cause, now)
+	}
+	return r.reloadInPlace(ctx, w, pr, name, hash, cause, now)
+}
+
+// reloadInPlace waits for the kubelet to sync the mounted volumes of w and
+// then reloads every ready pod with pr. Pods are tracked individually, so a
+// pod whose files are not synced yet is retried without reloading the others
+// twice. If any pod fails, or does not sync in time, w is restarted.
+func (r *reloader) reloadInPlace(ctx context.Context, w workload.Workload, pr strategy.PodReloader, name, hash, cause string, now time.Time) (time.Duration, error) {
+	obj := w.Object()
+	cfg := r.store.Get().Strategy
+	delay := r.syncDelay(w)
+
+	syncing, ok := getSyncingReload(obj)
+	if !ok || syncing.Hash != hash {
+		return delay, r.setSyncing(ctx, w, syncingReload{Hash: hash, Since: now.UTC().Truncate(time.Second)})
+	}
+	if wait := syncing.Since.Add(delay).Sub(now); wait > 0 {
+		return wait, nil
+	}
+
+	pods, err := workload.Pods(ctx, r.client, w)
+	if err != nil {
+		return 0, err
+	}
+
+	reloaded := map[string]bool{}
+	for _, pod := range syncing.Reloaded {
+		reloaded[pod] = true
+	}
+	var failed, notSynced []string
+	for i := range pods {
+		pod := &pods[i]
+		if !workload.Ready(pod) || reloaded[pod.Name] {
+			continue
+		}
+
+		podCtx, cancel := context.WithTimeout(ctx, cfg.Timeout.Duration)
+		err := pr.Reload(podCtx, pod)
+		cancel()
+		switch {
+		case err == nil:
+			reloaded[pod.Name] = true
+			syncing.Reloaded = append(syncing.Reloaded, pod.Name)
+		case errors.Is(err, strategy.ErrNotSynced):
+			notSynced = append(notSynced, pod.Name)
+		default:
+			log.FromContext(ctx).Error(err, "in-place reload failed", "pod", pod.Name, "strategy", name)
+			failed = append(failed, pod.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return 0, r.fallback(ctx, w, name, hash, cause, now,
+			fmt.Sprintf("%s reload failed on %d pods (%s)", name, len(failed), strings.Join(failed, ", ")))
+	}
+	if len(notSynced) > 0 {
+		if now.After(syncing.Since.Add(delay + cfg.SyncTimeout.Duration)) {
+			return 0, r.fallback(ctx, w, name, hash, cause, now,
+				fmt.Sprintf("mounted files of %d pods (%s) were not synced in time", len(notSynced), strings.Join(notSynced, ", ")))
+		}
+		return retryInterval, r.setSyncing(ctx, w, syncing)
+	}
+
+	return 0, r.commit(ctx, w, hash, name, now, nil,
+		fmt.Sprintf("Reloaded %d pods in place (%s): %s", len(syncing.Reloaded), name, cause))
+}
+
+// fallback restarts w after an in-place reload failed.
+func (r *reloader) fallback(ctx context.Context, w workload.Workload, name, hash, cause string, now time.Time, reason string) error {
+	obj := w.Object()
+
+	inPlaceFailuresTotal.WithLabelValues(obj.GetNamespace(), w.Kind(), obj.GetName(), name).Inc()
+	r.recorder.Eventf(obj, corev1.EventTypeWarning, "InPlaceReloadFailed", "%s, restarting instead", reason)
+	return r.restart(ctx, w, hash, cause, now)
+}
+
+// setSyncing records the progress of the in-place reload of w.
+func (r *reloader) setSyncing(ctx context.Context, w workload.Workload, syncing syncingReload) error {
+	obj := w.Object()
+
+	value, err := json.Marshal(syncing)
+	if err != nil {
+		return err
+	}
+	if obj.GetAnnotations()[annotation.SyncingReload] == string(value) {
+		return nil
+	}
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	setAnnotation(obj, annotation.SyncingReload, string(value))
+	return r.client.Patch(ctx, obj, patch)
+}
+
+// mountedFiles returns, for each container of w, the files mounted from
+// ConfigMap and Secret volumes with their current content. Files mounted
+// through subPath are left out since the kubelet never updates them.
+func (r *reloader) mountedFiles(ctx context.Context, w workload.Workload) (map[string][]strategy.MountedFile, error) {
+	spec := w.PodSpec()
+	namespace := w.Object().GetNamespace()
+
+	volumes := map[string][]workload.Projection{}
+	for _, v := range spec.Volumes {
+		volumes[v.Name] = workload.Projections(v)
+	}
+
+	files := map[string][]strategy.MountedFile{}
+	for _, c := range spec.Containers {
+		for _, m := range c.VolumeMounts {
+			if m.SubPath != "" || m.SubPathExpr != "" {
+				continue
+			}
+			for _, p := range volumes[m.Name] {
+				data, err := workload.SourceData(ctx, r.client, p.Reference, namespace)
+				if err != nil {
+					return nil, err
+				}
+				if data == nil {
+					continue
+				}
+				for _, item := range mountedItems(data, p.Items) {
+					content, ok := data[item.Key]
+					if !ok {
+						continue
+					}
+					files[c.Name] = append(files[c.Name], strategy.MountedFile{
+						Path:    path.Join(m.MountPath, item.Path),
+						Content: content,
+					})
+				}
+			}
+		}
+	}
+	return files, nil
+}
+
+// mountedItems returns the items of a volume, or one item per key of data
+// when the volume projects every key.
+func mountedItems(data map[string][]byte, items []corev1.KeyToPath) []corev1.KeyToPath {
+	if len(items) > 0 {
+		return items
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		items = append(items, corev1.KeyToPath{Key: k, Path: k})
+	}
+	return items
+}
+
+// syncDelay returns how long to wait for the kubelet before reloading w in place.
+func (r *reloader) syncDelay(w workload.Workload) time.Duration {
+	if v, ok := w.Object().GetAnnotations()[annotation.SyncDelay]; ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return r.store.Get().Strategy.SyncDelay.Duration
+}
+
+func getSyncingReload(obj client.Object) (syncingReload, bool) {
+	var syncing syncingReload
+	v, ok := obj.GetAnnotations()[annotation.SyncingReload]
+	if !ok || json.Unmarshal([]byte(v), &syncing) != nil {
+		return syncing, false
+	}
+	return syncing, true
+}
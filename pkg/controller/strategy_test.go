@@ -0,0 +1,88 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/strategy"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+var _ = Describe("In-place strategies", func() {
+	var (
+		ctx context.Context
+		r   *reloader
+		d   *appsv1.Deployment
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		d = &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Annotations: map[string]string{
+			annotation.Strategy: strategy.HTTP,
+			annotation.HTTPPort: "8080",
+		}}}
+		d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "web", EnvFrom: []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web"}}},
+		}}}
+	})
+
+	// apply 는 workload 를 in-place strategy 로 reload 하고 pod 가 restart 되었는지 반환
+	apply := func() bool {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Data: map[string]string{"A": "1"}}
+		r = &reloader{client: newFakeClient(d, cm), recorder: record.NewFakeRecorder(10), store: config.NewStore(config.NewConfig())}
+		w, _ := workload.New(d)
+		_, err := r.apply(ctx, w, nil, "hash", "test", time.Now())
+		Expect(err).NotTo(HaveOccurred())
+
+		after := &appsv1.Deployment{}
+		Expect(r.client.Get(ctx, client.ObjectKeyFromObject(d), after)).To(Succeed())
+		_, restarted := after.Spec.Template.Annotations[annotation.RestartedAt]
+		return restarted
+	}
+
+	It("reloads in place when the keys consumed through env are unchanged", func() {
+		w, _ := workload.New(d)
+		static, err := workload.StaticHash(ctx, newFakeClient(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Data: map[string]string{"A": "1"},
+		}), w)
+		Expect(err).NotTo(HaveOccurred())
+		d.Annotations[annotation.StaticHash] = static
+		Expect(apply()).To(BeFalse())
+	})
+
+	It("restarts when the keys consumed through env changed", func() {
+		d.Annotations[annotation.StaticHash] = "stale"
+		Expect(apply()).To(BeTrue())
+	})
+
+	It("restarts when the keys consumed through env were never recorded", func() {
+		Expect(apply()).To(BeTrue())
+	})
+})
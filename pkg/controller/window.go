@@ -0,0 +1,141 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/policy"
+	"github.com/hotkimho/reloader-server/project/pkg/window"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// pendingReload is the value of the pending reload annotation.
+type pendingReload struct {
+	Hash      string     `json:"hash"`
+	Reason    string     `json:"reason"`
+	Since     time.Time  `json:"since"`
+	NotBefore *time.Time `json:"notBefore,omitempty"`
+}
+
+// schedule returns the maintenance windows and freezes of ns. The windows
+// of the policies in s take precedence over the namespace annotations, which
+// take precedence over the controller config.
+func (r *reloader) schedule(ns *corev1.Namespace, s *policy.Settings) (*window.Schedule, error) {
+	cfg := r.store.Get().Windows
+	allowed := cfg.Allowed
+	freezes := cfg.Freezes
+
+	if v, ok := ns.Annotations[annotation.Windows]; ok {
+		var windows []config.MaintenanceWindow
+		err := json.Unmarshal([]byte(v), &windows)
+		if err == nil {
+			_, err = window.New(windows, nil)
+		}
+		// 잘못된 annotation 은 namespace 의 모든 reload 를 막지 않도록 controller 설정으로 대체
+		if err != nil {
+			r.recorder.Eventf(ns, corev1.EventTypeWarning, "InvalidWindows", "Ignoring %s: %v", annotation.Windows, err)
+		} else {
+			allowed = windows
+		}
+	}
+	if v, ok := ns.Annotations[annotation.Freezes]; ok {
+		var periods []config.FreezePeriod
+		err := json.Unmarshal([]byte(v), &periods)
+		if err == nil {
+			_, err = window.New(nil, periods)
+		}
+		if err != nil {
+			r.recorder.Eventf(ns, corev1.EventTypeWarning, "InvalidFreezes", "Ignoring %s: %v", annotation.Freezes, err)
+		} else {
+			freezes = periods
+		}
+	}
+
+	if windows := s.Windows(); windows != nil {
+		allowed = windows
+	}
+	return window.New(allowed, freezes)
+}
+
+// queue records a reload of w to hash that is not allowed before notBefore.
+// The record lives on the workload so that it survives controller restarts.
+func (r *reloader) queue(ctx context.Context, w workload.Workload, hash, reason string, notBefore time.Time) error {
+	obj := w.Object()
+
+	pending := pendingReload{Hash: hash, Reason: reason, Since: time.Now().UTC().Truncate(time.Second)}
+	if !notBefore.IsZero() {
+		t := notBefore.UTC()
+		pending.NotBefore = &t
+	}
+	if prev, ok := getPendingReload(obj); ok {
+		if prev.Hash == hash && prev.Reason == reason && sameTime(prev.NotBefore, pending.NotBefore) {
+			return nil
+		}
+		pending.Since = prev.Since
+	}
+
+	value, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	setAnnotation(obj, annotation.PendingReload, string(value))
+	if err := r.client.Patch(ctx, obj, patch); err != nil {
+		return err
+	}
+
+	r.recorder.Eventf(obj, corev1.EventTypeNormal, "ReloadQueued", "Reload queued (%s)", reason)
+	log.FromContext(ctx).Info("queued reload", "workload", w.Kind()+"/"+obj.GetName(), "reason", reason, "notBefore", notBefore)
+	return nil
+}
+
+// dropPendingReload removes a queued reload that is no longer needed.
+func (r *reloader) dropPendingReload(ctx context.Context, w workload.Workload) error {
+	obj := w.Object()
+	if _, ok := obj.GetAnnotations()[annotation.PendingReload]; !ok {
+		return nil
+	}
+
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	removeAnnotation(obj, annotation.PendingReload)
+	return r.client.Patch(ctx, obj, patch)
+}
+
+func getPendingReload(obj client.Object) (pendingReload, bool) {
+	var pending pendingReload
+	v, ok := obj.GetAnnotations()[annotation.PendingReload]
+	if !ok || json.Unmarshal([]byte(v), &pending) != nil {
+		return pending, false
+	}
+	return pending, true
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+)
+
+var _ = Describe("Maintenance windows", func() {
+	It("falls back to the controller windows when the namespace annotations are invalid", func() {
+		cfg := config.NewConfig()
+		cfg.Windows.Allowed = []config.MaintenanceWindow{{Schedule: "0 2 * * *", Duration: metav1.Duration{Duration: time.Hour}}}
+		recorder := record.NewFakeRecorder(10)
+		r := &reloader{client: newFakeClient(), recorder: recorder, store: config.NewStore(cfg)}
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Annotations: map[string]string{
+			annotation.Windows: `[{"schedule":"every night","duration":"1h"}]`,
+			annotation.Freezes: `[{"start":"2024-12-24T00:00:00Z","end":"2024-12-20T00:00:00Z"}]`,
+		}}}
+
+		schedule, err := r.schedule(ns, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(Receive(ContainSubstring("InvalidWindows")))
+		Expect(recorder.Events).To(Receive(ContainSubstring("InvalidFreezes")))
+
+		day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+		Expect(schedule.Allowed(day.Add(2*time.Hour + 30*time.Minute))).To(BeTrue())
+		Expect(schedule.Allowed(day.Add(12 * time.Hour))).To(BeFalse())
+	})
+})
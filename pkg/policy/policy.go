@@ -0,0 +1,303 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy evaluates the reload policies selecting sources and workloads.
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hotkimho/reloader-server/project/api/v1alpha1"
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// MatchesSource reports whether rules select the source of the given kind,
+// name and labels.
+func MatchesSource(rules *v1alpha1.PolicyRules, kind workload.SourceKind, name string, lbls map[string]string) bool {
+	for _, s := range rules.Sources {
+		if s.Kind != string(kind) || !matchesName(s.Names, name) || !matchesLabels(s.Selector, lbls) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// MatchesTarget reports whether rules select w.
+func MatchesTarget(rules *v1alpha1.PolicyRules, w workload.Workload) bool {
+	if len(rules.Targets) == 0 {
+		return true
+	}
+	for _, t := range rules.Targets {
+		if !matchesKind(t.Kinds, w.Kind()) || !matchesLabels(t.Selector, w.Object().GetLabels()) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// MatchesNamespace reports whether the namespace selector of a
+// ClusterReloadPolicy selects a namespace with the given labels.
+func MatchesNamespace(spec *v1alpha1.ClusterReloadPolicySpec, lbls map[string]string) bool {
+	return matchesLabels(spec.NamespaceSelector, lbls)
+}
+
+func matchesName(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, err := path.Match(p, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesKind(kinds []string, kind string) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesLabels reports whether selector selects lbls. An invalid selector
+// selects nothing.
+func matchesLabels(selector *metav1.LabelSelector, lbls map[string]string) bool {
+	if selector == nil {
+		return true
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	return s.Matches(labels.Set(lbls))
+}
+
+// Policy is a policy selecting a workload.
+type Policy struct {
+	// Name is the kind and name of the policy, e.g. "ReloadPolicy/web".
+	Name string
+	// Cluster is true for ClusterReloadPolicies.
+	Cluster bool
+	Rules   *v1alpha1.PolicyRules
+}
+
+// Merge merges policies in precedence order: ReloadPolicies before
+// ClusterReloadPolicies, then by descending priority, ties broken by name.
+// It returns nil when policies is empty.
+func Merge(policies []Policy) *Settings {
+	if len(policies) == 0 {
+		return nil
+	}
+	sorted := append([]Policy(nil), policies...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Cluster != b.Cluster {
+			return !a.Cluster
+		}
+		if a.Rules.Priority != b.Rules.Priority {
+			return a.Rules.Priority > b.Rules.Priority
+		}
+		return a.Name < b.Name
+	})
+	s := &Settings{}
+	for _, p := range sorted {
+		s.Add(p.Name, p.Rules)
+	}
+	return s
+}
+
+// Select returns the settings merged from the ReloadPolicies and
+// ClusterReloadPolicies selecting w, or nil when none does. Clusters without
+// the policy CRDs have no policies.
+func Select(ctx context.Context, c client.Reader, w workload.Workload) (*Settings, error) {
+	namespace := w.Object().GetNamespace()
+
+	var selected []Policy
+	list := &v1alpha1.ReloadPolicyList{}
+	if err := c.List(ctx, list, client.InNamespace(namespace)); err != nil && !meta.IsNoMatchError(err) {
+		return nil, err
+	}
+	for i := range list.Items {
+		p := &list.Items[i]
+		if p.DeletionTimestamp != nil || !MatchesTarget(&p.Spec.PolicyRules, w) {
+			continue
+		}
+		selected = append(selected, Policy{Name: "ReloadPolicy/" + p.Name, Rules: &p.Spec.PolicyRules})
+	}
+
+	clusterList := &v1alpha1.ClusterReloadPolicyList{}
+	if err := c.List(ctx, clusterList); err != nil && !meta.IsNoMatchError(err) {
+		return nil, err
+	}
+	if len(clusterList.Items) > 0 {
+		ns := &corev1.Namespace{}
+		if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+			// namespace 가 없으면 namespace 의 policy 만 적용
+			if apierrors.IsNotFound(err) {
+				return Merge(selected), nil
+			}
+			return nil, err
+		}
+		for i := range clusterList.Items {
+			p := &clusterList.Items[i]
+			if p.DeletionTimestamp != nil || !MatchesNamespace(&p.Spec, ns.Labels) ||
+				!MatchesTarget(&p.Spec.PolicyRules, w) {
+				continue
+			}
+			selected = append(selected, Policy{Name: "ClusterReloadPolicy/" + p.Name, Cluster: true, Rules: &p.Spec.PolicyRules})
+		}
+	}
+	return Merge(selected), nil
+}
+
+// Settings are the reload settings of a workload merged from the policies
+// selecting it. A nil Settings means no policy selects the workload.
+type Settings struct {
+	// Policies are the names of the merged policies, in precedence order.
+	Policies []string
+
+	rules      []*v1alpha1.PolicyRules
+	strategy   string
+	parameters *v1alpha1.StrategyParameters
+	debounce   time.Duration
+	cooldown   *time.Duration
+	windows    []config.MaintenanceWindow
+}
+
+// Add merges the rules of the named policy. Each setting is taken from the
+// first policy added that specifies it.
+func (s *Settings) Add(name string, rules *v1alpha1.PolicyRules) {
+	s.Policies = append(s.Policies, name)
+	s.rules = append(s.rules, rules)
+	// parameter 는 strategy 를 정한 policy 의 것만 사용
+	if s.strategy == "" {
+		s.strategy = rules.Strategy
+		s.parameters = rules.Parameters
+	}
+	if s.debounce == 0 && rules.Debounce != nil {
+		s.debounce = rules.Debounce.Duration
+	}
+	if s.cooldown == nil && rules.Cooldown != nil {
+		d := rules.Cooldown.Duration
+		s.cooldown = &d
+	}
+	if s.windows == nil && len(rules.Windows) > 0 {
+		for _, w := range rules.Windows {
+			s.windows = append(s.windows, config.MaintenanceWindow{Schedule: w.Schedule, Duration: w.Duration})
+		}
+	}
+}
+
+// Empty reports whether no policy was merged.
+func (s *Settings) Empty() bool {
+	return s == nil || len(s.rules) == 0
+}
+
+// Governs reports whether any merged policy selects the given source.
+func (s *Settings) Governs(kind workload.SourceKind, name string, lbls map[string]string) bool {
+	if s == nil {
+		return false
+	}
+	for _, rules := range s.rules {
+		if MatchesSource(rules, kind, name, lbls) {
+			return true
+		}
+	}
+	return false
+}
+
+// Annotations returns the workload annotations overlaid on the settings
+// expressed as annotations, so the annotations of the workload take precedence.
+func (s *Settings) Annotations(own map[string]string) map[string]string {
+	merged := map[string]string{}
+	if s != nil {
+		if s.strategy != "" {
+			merged[annotation.Strategy] = s.strategy
+		}
+		if p := s.parameters; p != nil {
+			parameterAnnotations(p, merged)
+		}
+		if s.cooldown != nil {
+			merged[annotation.Cooldown] = s.cooldown.String()
+		}
+	}
+	for k, v := range own {
+		merged[k] = v
+	}
+	return merged
+}
+
+// parameterAnnotations expresses the parameters p as strategy annotations.
+func parameterAnnotations(p *v1alpha1.StrategyParameters, annotations map[string]string) {
+	set := func(key, value string) {
+		if value != "" {
+			annotations[key] = value
+		}
+	}
+	if p.HTTPPort != nil {
+		set(annotation.HTTPPort, p.HTTPPort.String())
+	}
+	set(annotation.HTTPPath, p.HTTPPath)
+	set(annotation.HTTPMethod, p.HTTPMethod)
+	if len(p.HTTPHeaders) > 0 {
+		headers, _ := json.Marshal(p.HTTPHeaders)
+		set(annotation.HTTPHeaders, string(headers))
+	}
+	if len(p.Command) > 0 {
+		command, _ := json.Marshal(p.Command)
+		set(annotation.ExecCommand, string(command))
+	}
+	set(annotation.ExecContainer, p.Container)
+	set(annotation.Signal, p.Signal)
+}
+
+// Debounce returns how long the sources must stay unchanged before a reload.
+func (s *Settings) Debounce() time.Duration {
+	if s == nil {
+		return 0
+	}
+	return s.debounce
+}
+
+// Windows returns the maintenance windows of the policies, or nil when none
+// specifies any.
+func (s *Settings) Windows() []config.MaintenanceWindow {
+	if s == nil {
+		return nil
+	}
+	return s.windows
+}
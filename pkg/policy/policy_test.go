@@ -0,0 +1,125 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/hotkimho/reloader-server/project/api/v1alpha1"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+var _ = Describe("Policy", func() {
+	rules := &v1alpha1.PolicyRules{
+		Sources: []v1alpha1.SourceSelector{
+			{Kind: "ConfigMap", Names: []string{"app-*"}},
+			{Kind: "Secret", Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"watch": "true"}}},
+		},
+		Targets: []v1alpha1.TargetSelector{{
+			Kinds:    []string{"Deployment"},
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "web"}},
+		}},
+		Strategy: "http",
+		Cooldown: &metav1.Duration{Duration: time.Minute},
+	}
+
+	newWorkload := func(labels map[string]string) workload.Workload {
+		w, _ := workload.New(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Labels: labels}})
+		return w
+	}
+
+	It("matches sources by kind, name pattern and labels", func() {
+		Expect(MatchesSource(rules, workload.ConfigMap, "app-config", nil)).To(BeTrue())
+		Expect(MatchesSource(rules, workload.ConfigMap, "db-config", nil)).To(BeFalse())
+		Expect(MatchesSource(rules, workload.Secret, "app-config", nil)).To(BeFalse())
+		Expect(MatchesSource(rules, workload.Secret, "tls", map[string]string{"watch": "true"})).To(BeTrue())
+	})
+
+	It("matches targets by kind and labels", func() {
+		Expect(MatchesTarget(rules, newWorkload(map[string]string{"tier": "web"}))).To(BeTrue())
+		Expect(MatchesTarget(rules, newWorkload(map[string]string{"tier": "db"}))).To(BeFalse())
+		Expect(MatchesTarget(&v1alpha1.PolicyRules{}, newWorkload(nil))).To(BeTrue())
+	})
+
+	It("merges settings by precedence under the workload annotations", func() {
+		s := &Settings{}
+		s.Add("first", &v1alpha1.PolicyRules{Cooldown: &metav1.Duration{Duration: 5 * time.Minute}})
+		s.Add("second", rules)
+
+		Expect(s.Policies).To(Equal([]string{"first", "second"}))
+		Expect(s.Annotations(nil)).To(Equal(map[string]string{
+			annotation.Strategy: "http",
+			annotation.Cooldown: "5m0s",
+		}))
+		Expect(s.Annotations(map[string]string{annotation.Strategy: "restart"})).To(HaveKeyWithValue(annotation.Strategy, "restart"))
+		Expect(s.Governs(workload.ConfigMap, "app-config", nil)).To(BeTrue())
+	})
+
+	It("orders namespaced policies first, then by priority and name", func() {
+		s := Merge([]Policy{
+			{Name: "ClusterReloadPolicy/defaults", Cluster: true, Rules: &v1alpha1.PolicyRules{Priority: 100, Strategy: "signal"}},
+			{Name: "ReloadPolicy/b", Rules: &v1alpha1.PolicyRules{}},
+			{Name: "ReloadPolicy/c", Rules: &v1alpha1.PolicyRules{Priority: 1}},
+			{Name: "ReloadPolicy/a", Rules: &v1alpha1.PolicyRules{}},
+		})
+
+		Expect(s.Policies).To(Equal([]string{
+			"ReloadPolicy/c", "ReloadPolicy/a", "ReloadPolicy/b", "ClusterReloadPolicy/defaults",
+		}))
+		Expect(s.Annotations(nil)).To(HaveKeyWithValue(annotation.Strategy, "signal"))
+		Expect(Merge(nil)).To(BeNil())
+	})
+
+	It("matches namespaces by selector", func() {
+		spec := &v1alpha1.ClusterReloadPolicySpec{}
+		Expect(MatchesNamespace(spec, nil)).To(BeTrue())
+
+		spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}}
+		Expect(MatchesNamespace(spec, map[string]string{"tenant": "true"})).To(BeTrue())
+		Expect(MatchesNamespace(spec, nil)).To(BeFalse())
+	})
+
+	It("expresses the parameters of the strategy as annotations", func() {
+		port := intstr.FromInt32(8080)
+		s := &Settings{}
+		s.Add("ReloadPolicy/web", &v1alpha1.PolicyRules{Strategy: "exec", Parameters: &v1alpha1.StrategyParameters{
+			Command: []string{"nginx", "-s", "reload"}, Container: "nginx",
+		}})
+		// strategy 를 정하지 않은 policy 의 parameter 는 무시
+		s.Add("ClusterReloadPolicy/all", &v1alpha1.PolicyRules{Parameters: &v1alpha1.StrategyParameters{HTTPPort: &port}})
+		Expect(s.Annotations(map[string]string{annotation.ExecContainer: "app"})).To(Equal(map[string]string{
+			annotation.Strategy:      "exec",
+			annotation.ExecCommand:   `["nginx","-s","reload"]`,
+			annotation.ExecContainer: "app",
+		}))
+	})
+
+	It("treats nil settings as no policy", func() {
+		var s *Settings
+		Expect(s.Empty()).To(BeTrue())
+		Expect(s.Debounce()).To(BeZero())
+		Expect(s.Annotations(map[string]string{"a": "b"})).To(Equal(map[string]string{"a": "b"}))
+	})
+})
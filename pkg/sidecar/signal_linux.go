@@ -0,0 +1,97 @@
+//go:build linux
+
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+var signals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"TERM": syscall.SIGTERM,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+}
+
+// SignalTrigger signals a process of the application container. The pod
+// must share its process namespace (shareProcessNamespace: true).
+type SignalTrigger struct {
+	// Process is the name of the process to signal, as in /proc/<pid>/comm.
+	Process string
+	Signal  syscall.Signal
+	// ProcRoot is the mount point of procfs, /proc by default.
+	ProcRoot string
+}
+
+// NewSignalTrigger returns a trigger sending the named signal, e.g. "HUP".
+func NewSignalTrigger(process, signal string) (Trigger, error) {
+	sig, ok := signals[strings.TrimPrefix(strings.ToUpper(signal), "SIG")]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signal %q", signal)
+	}
+	return &SignalTrigger{Process: process, Signal: sig, ProcRoot: "/proc"}, nil
+}
+
+func (t *SignalTrigger) Trigger(_ context.Context) error {
+	pids, err := t.find()
+	if err != nil {
+		return err
+	}
+	if len(pids) == 0 {
+		return fmt.Errorf("no process named %q found", t.Process)
+	}
+	for _, pid := range pids {
+		if err := syscall.Kill(pid, t.Signal); err != nil {
+			return fmt.Errorf("signal %d: %w", pid, err)
+		}
+	}
+	return nil
+}
+
+// find returns the pids of the processes named Process, except our own.
+func (t *SignalTrigger) find() ([]int, error) {
+	entries, err := os.ReadDir(t.ProcRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var pids []int
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		comm, err := os.ReadFile(filepath.Join(t.ProcRoot, e.Name(), "comm"))
+		if err != nil {
+			continue
+		}
+		if strings.TrimSpace(string(comm)) == t.Process {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
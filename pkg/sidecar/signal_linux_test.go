@@ -0,0 +1,44 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SignalTrigger", func() {
+	It("finds processes by name", func() {
+		proc := GinkgoT().TempDir()
+		for pid, comm := range map[string]string{"10": "nginx\n", "11": "sh\n", "12": "nginx\n", "self": "x\n"} {
+			Expect(os.Mkdir(filepath.Join(proc, pid), 0o755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(proc, pid, "comm"), []byte(comm), 0o644)).To(Succeed())
+		}
+
+		t := &SignalTrigger{Process: "nginx", Signal: syscall.SIGHUP, ProcRoot: proc}
+		Expect(t.find()).To(ConsistOf(10, 12))
+	})
+
+	It("rejects unknown signals", func() {
+		_, err := NewSignalTrigger("nginx", "SIGFOO")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,73 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// Trigger reloads the application after its configuration changed.
+type Trigger interface {
+	Trigger(ctx context.Context) error
+}
+
+// HTTPTrigger calls a reload endpoint, e.g. POST http://localhost:9090/-/reload.
+type HTTPTrigger struct {
+	URL    string
+	Method string
+	Client *http.Client
+}
+
+func (t *HTTPTrigger) Trigger(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, t.Method, t.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s returned %s", t.Method, t.URL, resp.Status)
+	}
+	return nil
+}
+
+// CommandTrigger runs a command in the sidecar container.
+type CommandTrigger struct {
+	Command []string
+}
+
+func (t *CommandTrigger) Trigger(ctx context.Context) error {
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.Command[0], t.Command[1:]...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", strings.Join(t.Command, " "), err, strings.TrimSpace(out.String()))
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sidecar watches mounted ConfigMap and Secret directories and
+// triggers a local reload of the application sharing the pod.
+package sidecar
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+)
+
+// dataDir is the symlink the kubelet atomically swaps to publish new content
+// of a ConfigMap or Secret volume.
+const dataDir = "..data"
+
+// retryInterval is how long to wait before retrying a failed reload.
+const retryInterval = 10 * time.Second
+
+// Watcher triggers a reload when the content of any of its directories changes.
+type Watcher struct {
+	Dirs []string
+	// Debounce coalesces the burst of events of a single update.
+	Debounce time.Duration
+	Trigger  Trigger
+	Log      logr.Logger
+}
+
+// Run watches the directories until ctx is done.
+func (w *Watcher) Run(ctx context.Context) error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+
+	for _, dir := range w.Dirs {
+		if err := fw.Add(dir); err != nil {
+			return err
+		}
+	}
+
+	last, err := w.hash()
+	if err != nil {
+		return err
+	}
+	w.Log.Info("watching directories", "dirs", w.Dirs, "hash", last)
+
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return nil
+			}
+			w.Log.Error(err, "watch error")
+		case event, ok := <-fw.Events:
+			if !ok {
+				return nil
+			}
+			if relevant(event) {
+				timer.Reset(w.Debounce)
+			}
+		case <-timer.C:
+			current, err := w.hash()
+			if err != nil {
+				w.Log.Error(err, "unable to read watched directories")
+				continue
+			}
+			if current == last {
+				continue
+			}
+			w.Log.Info("configuration changed, triggering reload", "hash", current)
+			if err := w.Trigger.Trigger(ctx); err != nil {
+				w.Log.Error(err, "reload failed, retrying", "after", retryInterval)
+				timer.Reset(retryInterval)
+				continue
+			}
+			last = current
+		}
+	}
+}
+
+// relevant filters the events of a watched directory. In a kubelet managed
+// volume only the swap of the ..data symlink publishes new content, every
+// other hidden entry is an intermediate step of the atomic writer.
+func relevant(event fsnotify.Event) bool {
+	name := filepath.Base(event.Name)
+	if name == dataDir {
+		return event.Has(fsnotify.Create)
+	}
+	if strings.HasPrefix(name, "..") {
+		return false
+	}
+	return event.Has(fsnotify.Create) || event.Has(fsnotify.Write) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)
+}
+
+// hash digests the visible files of the directories, following symlinks.
+func (w *Watcher) hash() (string, error) {
+	h := sha256.New()
+	for _, dir := range w.Dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return "", err
+		}
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			if !strings.HasPrefix(e.Name(), "..") {
+				names = append(names, e.Name())
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			info, err := os.Stat(path)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return "", err
+			}
+			if info.IsDir() {
+				continue
+			}
+			if err := hashFile(h, path); err != nil && !os.IsNotExist(err) {
+				return "", err
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(h io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, f); err != nil {
+		return err
+	}
+	_, err = io.WriteString(h, path+"="+hex.EncodeToString(sum.Sum(nil))+"\n")
+	return err
+}
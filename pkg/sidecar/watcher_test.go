@@ -0,0 +1,130 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+)
+
+type countingTrigger struct {
+	count atomic.Int32
+}
+
+func (t *countingTrigger) Trigger(context.Context) error {
+	t.count.Add(1)
+	return nil
+}
+
+// publish mimics the kubelet atomic writer: write a new timestamped
+// directory, point ..data_tmp at it and rename it over ..data.
+func publish(dir string, version int, files map[string]string) {
+	ts := fmt.Sprintf("..2024_01_01_00_00_%02d.000", version)
+	Expect(os.Mkdir(filepath.Join(dir, ts), 0o755)).To(Succeed())
+	for name, content := range files {
+		Expect(os.WriteFile(filepath.Join(dir, ts, name), []byte(content), 0o644)).To(Succeed())
+	}
+	Expect(os.Symlink(ts, filepath.Join(dir, "..data_tmp"))).To(Succeed())
+	Expect(os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, dataDir))).To(Succeed())
+	for name := range files {
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); os.IsNotExist(err) {
+			Expect(os.Symlink(filepath.Join(dataDir, name), link)).To(Succeed())
+		}
+	}
+}
+
+var _ = Describe("Watcher", func() {
+	var (
+		dir     string
+		trigger *countingTrigger
+		cancel  context.CancelFunc
+		done    chan struct{}
+	)
+
+	start := func() {
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		done = make(chan struct{})
+		w := &Watcher{Dirs: []string{dir}, Debounce: 50 * time.Millisecond, Trigger: trigger, Log: logr.Discard()}
+		go func() {
+			defer close(done)
+			defer GinkgoRecover()
+			Expect(w.Run(ctx)).To(Succeed())
+		}()
+		// fsnotify 에 watch 가 등록될 때까지 대기
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		trigger = &countingTrigger{}
+	})
+
+	AfterEach(func() {
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("triggers once per kubelet ..data swap", func() {
+		publish(dir, 1, map[string]string{"app.conf": "a=1"})
+		start()
+
+		publish(dir, 2, map[string]string{"app.conf": "a=2"})
+		Eventually(trigger.count.Load).Should(BeEquivalentTo(1))
+		Consistently(trigger.count.Load, 300*time.Millisecond).Should(BeEquivalentTo(1))
+	})
+
+	It("ignores a swap that does not change the content", func() {
+		publish(dir, 1, map[string]string{"app.conf": "a=1"})
+		start()
+
+		publish(dir, 2, map[string]string{"app.conf": "a=1"})
+		Consistently(trigger.count.Load, 300*time.Millisecond).Should(BeEquivalentTo(0))
+	})
+
+	It("triggers on plain file writes", func() {
+		Expect(os.WriteFile(filepath.Join(dir, "app.conf"), []byte("a=1"), 0o644)).To(Succeed())
+		start()
+
+		Expect(os.WriteFile(filepath.Join(dir, "app.conf"), []byte("a=2"), 0o644)).To(Succeed())
+		Eventually(trigger.count.Load).Should(BeEquivalentTo(1))
+	})
+})
+
+var _ = Describe("CommandTrigger", func() {
+	It("runs the command", func() {
+		marker := filepath.Join(GinkgoT().TempDir(), "reloaded")
+		t := &CommandTrigger{Command: []string{"touch", marker}}
+		Expect(t.Trigger(context.Background())).To(Succeed())
+		Expect(marker).To(BeAnExistingFile())
+	})
+
+	It("reports a failing command", func() {
+		t := &CommandTrigger{Command: []string{"false"}}
+		Expect(t.Trigger(context.Background())).NotTo(Succeed())
+	})
+})
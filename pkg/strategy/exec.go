@@ -0,0 +1,204 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+)
+
+// ErrNotSynced is returned when the mounted files of a pod do not carry the
+// latest configuration yet. The reload should be retried later.
+var ErrNotSynced = errors.New("mounted files are not synced yet")
+
+// Executor runs a command in a container through the pods/exec subresource.
+type Executor interface {
+	Exec(ctx context.Context, pod *corev1.Pod, container string, command []string) (stdout []byte, err error)
+}
+
+// MountedFile is a file a container reads its configuration from, with the
+// content the kubelet is expected to sync.
+type MountedFile struct {
+	Path    string
+	Content []byte
+}
+
+// execReloader runs a command, e.g. "nginx -s reload", in a container of the pod.
+type execReloader struct {
+	exec      Executor
+	container string
+	command   []string
+	// files maps containers to the files verified before the command runs.
+	files map[string][]MountedFile
+}
+
+func newExecReloader(annotations map[string]string, opts Options) (*execReloader, error) {
+	v, ok := annotations[annotation.ExecCommand]
+	if !ok {
+		return nil, fmt.Errorf("the %s strategy requires the %s annotation", Exec, annotation.ExecCommand)
+	}
+	command, err := parseCommand(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", annotation.ExecCommand, err)
+	}
+	return newCommandReloader(annotations, opts, command)
+}
+
+// newSignalReloader sends a signal to the main process of the container,
+// for apps reloading on e.g. SIGHUP.
+func newSignalReloader(annotations map[string]string, opts Options) (*execReloader, error) {
+	signal := "HUP"
+	if v, ok := annotations[annotation.Signal]; ok {
+		signal = strings.TrimPrefix(strings.ToUpper(v), "SIG")
+	}
+	return newCommandReloader(annotations, opts, []string{"kill", "-" + signal, "1"})
+}
+
+func newCommandReloader(annotations map[string]string, opts Options, command []string) (*execReloader, error) {
+	if opts.Executor == nil {
+		return nil, errors.New("pod exec is not available")
+	}
+	return &execReloader{
+		exec:      opts.Executor,
+		container: annotations[annotation.ExecContainer],
+		command:   command,
+		files:     opts.Files,
+	}, nil
+}
+
+func (e *execReloader) Reload(ctx context.Context, pod *corev1.Pod) error {
+	container := e.container
+	if container == "" {
+		container = defaultContainer(pod)
+	}
+
+	if files := e.files[container]; len(files) > 0 {
+		synced, err := e.synced(ctx, pod, container, files)
+		if err != nil {
+			return err
+		}
+		if !synced {
+			return ErrNotSynced
+		}
+	}
+
+	if _, err := e.exec.Exec(ctx, pod, container, e.command); err != nil {
+		return fmt.Errorf("%s in %s/%s: %w", strings.Join(e.command, " "), pod.Name, container, err)
+	}
+	return nil
+}
+
+// synced reports whether the container sees the expected content of files.
+func (e *execReloader) synced(ctx context.Context, pod *corev1.Pod, container string, files []MountedFile) (bool, error) {
+	command := []string{"cat", "--"}
+	expected := sha256.New()
+	for _, f := range files {
+		command = append(command, f.Path)
+		expected.Write(f.Content)
+	}
+
+	out, err := e.exec.Exec(ctx, pod, container, command)
+	if err != nil {
+		return false, fmt.Errorf("reading mounted files in %s/%s: %w", pod.Name, container, err)
+	}
+	actual := sha256.Sum256(out)
+	return bytes.Equal(actual[:], expected.Sum(nil)), nil
+}
+
+// defaultContainer returns the container named by the kubectl default
+// container annotation, or the first container of pod.
+func defaultContainer(pod *corev1.Pod) string {
+	if name, ok := pod.Annotations["kubectl.kubernetes.io/default-container"]; ok {
+		return name
+	}
+	if len(pod.Spec.Containers) > 0 {
+		return pod.Spec.Containers[0].Name
+	}
+	return ""
+}
+
+// parseCommand accepts a JSON array or a whitespace separated command.
+func parseCommand(v string) ([]string, error) {
+	var command []string
+	if strings.HasPrefix(strings.TrimSpace(v), "[") {
+		if err := json.Unmarshal([]byte(v), &command); err != nil {
+			return nil, err
+		}
+	} else {
+		command = strings.Fields(v)
+	}
+	if len(command) == 0 {
+		return nil, errors.New("empty command")
+	}
+	return command, nil
+}
+
+// podExecutor implements Executor with the SPDY remote command protocol.
+type podExecutor struct {
+	config *rest.Config
+	client kubernetes.Interface
+}
+
+// NewExecutor returns an Executor talking to the API server of config.
+func NewExecutor(config *rest.Config) (Executor, error) {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &podExecutor{config: config, client: client}, nil
+}
+
+func (p *podExecutor) Exec(ctx context.Context, pod *corev1.Pod, container string, command []string) ([]byte, error) {
+	req := p.client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(p.config, "POST", req.URL())
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr}); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
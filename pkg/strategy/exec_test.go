@@ -0,0 +1,114 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+)
+
+// fakeExecutor serves cat from files and records every other command.
+type fakeExecutor struct {
+	files    map[string]string
+	commands [][]string
+	err      error
+}
+
+func (f *fakeExecutor) Exec(_ context.Context, _ *corev1.Pod, container string, command []string) ([]byte, error) {
+	if command[0] == "cat" {
+		var out strings.Builder
+		for _, p := range command[2:] {
+			out.WriteString(f.files[p])
+		}
+		return []byte(out.String()), nil
+	}
+	f.commands = append(f.commands, append([]string{container}, command...))
+	return nil, f.err
+}
+
+var _ = Describe("Exec strategy", func() {
+	var (
+		executor *fakeExecutor
+		pod      *corev1.Pod
+		files    map[string][]MountedFile
+	)
+
+	BeforeEach(func() {
+		executor = &fakeExecutor{files: map[string]string{"/etc/nginx/nginx.conf": "new"}}
+		pod = &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx"}, {Name: "exporter"}}}}
+		files = map[string][]MountedFile{"nginx": {{Path: "/etc/nginx/nginx.conf", Content: []byte("new")}}}
+	})
+
+	It("runs the command once the mounted files are synced", func() {
+		pr, err := New(map[string]string{
+			annotation.Strategy:    Exec,
+			annotation.ExecCommand: "nginx -s reload",
+		}, Options{Executor: executor, Files: files})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(pr.Reload(context.Background(), pod)).To(Succeed())
+		Expect(executor.commands).To(Equal([][]string{{"nginx", "nginx", "-s", "reload"}}))
+	})
+
+	It("waits for stale files", func() {
+		executor.files["/etc/nginx/nginx.conf"] = "old"
+		pr, err := New(map[string]string{
+			annotation.Strategy:    Exec,
+			annotation.ExecCommand: `["nginx", "-s", "reload"]`,
+		}, Options{Executor: executor, Files: files})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(pr.Reload(context.Background(), pod)).To(MatchError(ErrNotSynced))
+		Expect(executor.commands).To(BeEmpty())
+	})
+
+	It("reports a failing command", func() {
+		executor.err = errors.New("exit code 1")
+		pr, err := New(map[string]string{
+			annotation.Strategy:    Exec,
+			annotation.ExecCommand: "nginx -s reload",
+		}, Options{Executor: executor})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(pr.Reload(context.Background(), pod)).NotTo(Succeed())
+	})
+
+	It("signals the main process of the configured container", func() {
+		pr, err := New(map[string]string{
+			annotation.Strategy:      Signal,
+			annotation.Signal:        "SIGUSR1",
+			annotation.ExecContainer: "exporter",
+		}, Options{Executor: executor, Files: files})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(pr.Reload(context.Background(), pod)).To(Succeed())
+		Expect(executor.commands).To(Equal([][]string{{"exporter", "kill", "-USR1", "1"}}))
+	})
+
+	It("requires a command", func() {
+		_, err := New(map[string]string{annotation.Strategy: Exec}, Options{Executor: executor})
+		Expect(err).To(HaveOccurred())
+	})
+})
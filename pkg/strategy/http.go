@@ -0,0 +1,115 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+)
+
+const (
+	defaultHTTPPath   = "/-/reload"
+	defaultHTTPMethod = http.MethodPost
+)
+
+// httpReloader calls a reload endpoint served by the pod, e.g. POST /-/reload.
+type httpReloader struct {
+	port    intstr.IntOrString
+	path    string
+	method  string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newHTTPReloader(annotations map[string]string, opts Options) (*httpReloader, error) {
+	port, ok := annotations[annotation.HTTPPort]
+	if !ok {
+		return nil, fmt.Errorf("the %s strategy requires the %s annotation", HTTP, annotation.HTTPPort)
+	}
+
+	h := &httpReloader{
+		port:   intstr.Parse(port),
+		path:   defaultHTTPPath,
+		method: defaultHTTPMethod,
+		client: &http.Client{Timeout: opts.Timeout},
+	}
+	if v, ok := annotations[annotation.HTTPPath]; ok {
+		h.path = v
+	}
+	if v, ok := annotations[annotation.HTTPMethod]; ok {
+		h.method = v
+	}
+	if v, ok := annotations[annotation.HTTPHeaders]; ok {
+		if err := json.Unmarshal([]byte(v), &h.headers); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", annotation.HTTPHeaders, err)
+		}
+	}
+	return h, nil
+}
+
+func (h *httpReloader) Reload(ctx context.Context, pod *corev1.Pod) error {
+	port, err := containerPort(pod, h.port)
+	if err != nil {
+		return err
+	}
+
+	url := "http://" + net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(port))) + h.path
+	req, err := http.NewRequestWithContext(ctx, h.method, url, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range h.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s returned %s", h.method, url, resp.Status)
+	}
+	return nil
+}
+
+// containerPort resolves a numeric or named container port of pod.
+func containerPort(pod *corev1.Pod, port intstr.IntOrString) (int32, error) {
+	if port.Type == intstr.Int {
+		return port.IntVal, nil
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == port.StrVal {
+				return p.ContainerPort, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("pod %s has no container port named %q", pod.Name, port.StrVal)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+)
+
+var _ = Describe("HTTP strategy", func() {
+	var (
+		server *httptest.Server
+		calls  []*http.Request
+		status int
+		pod    *corev1.Pod
+	)
+
+	BeforeEach(func() {
+		calls = nil
+		status = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, r)
+			w.WriteHeader(status)
+		}))
+
+		host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		portNum, _ := strconv.Atoi(port)
+		pod = &corev1.Pod{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Ports: []corev1.ContainerPort{{Name: "web", ContainerPort: int32(portNum)}},
+			}}},
+			Status: corev1.PodStatus{PodIP: host},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newReloader := func(annotations map[string]string) PodReloader {
+		annotations[annotation.Strategy] = HTTP
+		pr, err := New(annotations, Options{Timeout: time.Second})
+		Expect(err).NotTo(HaveOccurred())
+		return pr
+	}
+
+	It("calls the reload endpoint on the named port", func() {
+		pr := newReloader(map[string]string{
+			annotation.HTTPPort:    "web",
+			annotation.HTTPHeaders: `{"X-Reload-Token": "secret"}`,
+		})
+
+		Expect(pr.Reload(context.Background(), pod)).To(Succeed())
+		Expect(calls).To(HaveLen(1))
+		Expect(calls[0].Method).To(Equal(http.MethodPost))
+		Expect(calls[0].URL.Path).To(Equal("/-/reload"))
+		Expect(calls[0].Header.Get("X-Reload-Token")).To(Equal("secret"))
+	})
+
+	It("fails on an error status", func() {
+		status = http.StatusInternalServerError
+		pr := newReloader(map[string]string{annotation.HTTPPort: "web", annotation.HTTPPath: "/reload", annotation.HTTPMethod: "PUT"})
+
+		Expect(pr.Reload(context.Background(), pod)).NotTo(Succeed())
+		Expect(calls[0].Method).To(Equal(http.MethodPut))
+		Expect(calls[0].URL.Path).To(Equal("/reload"))
+	})
+
+	It("requires a port", func() {
+		_, err := New(map[string]string{annotation.Strategy: HTTP}, Options{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects unknown strategies", func() {
+		_, err := New(map[string]string{annotation.Strategy: "magic"}, Options{})
+		Expect(err).To(HaveOccurred())
+	})
+})
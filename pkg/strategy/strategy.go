@@ -0,0 +1,97 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package strategy implements the ways a running pod can pick up new
+// configuration without being restarted.
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+)
+
+// Names of the reload strategies, set with the strategy annotation.
+const (
+	// Restart rolls the pods of the workload. It is the default.
+	Restart = "restart"
+	// HTTP calls a reload endpoint on every ready pod.
+	HTTP = "http"
+	// Exec runs a command in a container of every ready pod.
+	Exec = "exec"
+	// Signal sends a signal to the main process of a container of every ready pod.
+	Signal = "signal"
+)
+
+// PodReloader reloads the configuration of a running pod in place.
+type PodReloader interface {
+	Reload(ctx context.Context, pod *corev1.Pod) error
+}
+
+// Options are the controller wide settings of the in-place strategies.
+type Options struct {
+	// Timeout bounds the reload of a single pod.
+	Timeout time.Duration
+	// Executor runs commands in containers for the exec and signal strategies.
+	Executor Executor
+	// Files are the mounted configuration files of each container, checked
+	// before running a command so it never reloads stale content.
+	Files map[string][]MountedFile
+}
+
+// Name returns the strategy selected by the annotations of a workload.
+func Name(annotations map[string]string) string {
+	if v, ok := annotations[annotation.Strategy]; ok {
+		return v
+	}
+	return Restart
+}
+
+// New returns the PodReloader configured by the annotations of a workload,
+// or nil for the restart strategy.
+func New(annotations map[string]string, opts Options) (PodReloader, error) {
+	switch name := Name(annotations); name {
+	case Restart:
+		return nil, nil
+	case HTTP:
+		return newHTTPReloader(annotations, opts)
+	case Exec:
+		return newExecReloader(annotations, opts)
+	case Signal:
+		return newSignalReloader(annotations, opts)
+	default:
+		return nil, fmt.Errorf("unknown reload strategy %q", name)
+	}
+}
+
+// Validate reports whether the annotations of a workload configure a valid
+// strategy, without the controller wide settings.
+func Validate(annotations map[string]string) error {
+	_, err := New(annotations, Options{Executor: nopExecutor{}})
+	return err
+}
+
+// nopExecutor stands in for the pod executor when only validating.
+type nopExecutor struct{}
+
+func (nopExecutor) Exec(context.Context, *corev1.Pod, string, []string) ([]byte, error) {
+	return nil, errors.New("pod exec is not available")
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/approval"
+)
+
+// +kubebuilder:webhook:path=/mutate--v1-configmap,mutating=true,failurePolicy=fail,sideEffects=None,groups="",resources=configmaps,verbs=create;update,versions=v1,name=mconfigmap.reloader.accordions.edu,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate--v1-secret,mutating=true,failurePolicy=fail,sideEffects=None,groups="",resources=secrets,verbs=create;update,versions=v1,name=msecret.reloader.accordions.edu,admissionReviewVersions=v1
+
+// ApprovalStamper records the authenticated user changing the content of a
+// critical ConfigMap or Secret, and the one deciding on its pending change,
+// so that neither can be forged and the controller can turn down changes
+// approved by their own author. Only critical sources are sent to it, and
+// their updates are refused while it is unavailable.
+type ApprovalStamper struct{}
+
+var _ admission.CustomDefaulter = &ApprovalStamper{}
+
+func (s *ApprovalStamper) Default(ctx context.Context, obj runtime.Object) error {
+	src, ok := obj.(client.Object)
+	if !ok || !approval.Critical(src) {
+		return nil
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	user := req.UserInfo.Username
+
+	var oldAnnotations map[string]string
+	changed := true
+	if req.Operation == admissionv1.Update {
+		old := reflect.New(reflect.TypeOf(src).Elem()).Interface().(client.Object)
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return fmt.Errorf("decoding old object: %w", err)
+		}
+		oldAnnotations = old.GetAnnotations()
+		changed = !equality.Semantic.DeepEqual(content(old), content(src))
+	}
+
+	annotations := src.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	// 내용을 바꾸지 않은 update 는 이전 작성자를 유지
+	switch prev, ok := oldAnnotations[annotation.ChangedBy]; {
+	case changed:
+		annotations[annotation.ChangedBy] = user
+	case ok:
+		annotations[annotation.ChangedBy] = prev
+	default:
+		delete(annotations, annotation.ChangedBy)
+	}
+	src.SetAnnotations(annotations)
+
+	if annotations[annotation.Approval] == oldAnnotations[annotation.Approval] {
+		return nil
+	}
+	decision, ok := approval.GetDecision(src)
+	if !ok {
+		return nil
+	}
+	decision.Approver = user
+	return approval.Set(src, annotation.Approval, decision)
+}
+
+// content returns the data of a ConfigMap or Secret.
+func content(src client.Object) []any {
+	switch s := src.(type) {
+	case *corev1.ConfigMap:
+		return []any{s.Data, s.BinaryData}
+	case *corev1.Secret:
+		return []any{s.Data, s.StringData}
+	}
+	return nil
+}
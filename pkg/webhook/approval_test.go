@@ -0,0 +1,90 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/approval"
+)
+
+var _ = Describe("Approval stamper", func() {
+	var (
+		old *corev1.ConfigMap
+		s   = &ApprovalStamper{}
+	)
+
+	BeforeEach(func() {
+		old = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default",
+				Labels: map[string]string{annotation.Critical: "true"}, Annotations: map[string]string{annotation.ChangedBy: "carol"}},
+			Data: map[string]string{"level": "info"},
+		}
+	})
+
+	updateBy := func(user string) context.Context {
+		raw, err := json.Marshal(old)
+		Expect(err).NotTo(HaveOccurred())
+		return admission.NewContextWithRequest(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Update,
+			OldObject: runtime.RawExtension{Raw: raw},
+			UserInfo:  authenticationv1.UserInfo{Username: user},
+		}})
+	}
+
+	It("records the user changing the content", func() {
+		cm := old.DeepCopy()
+		cm.Data["level"] = "debug"
+		Expect(s.Default(updateBy("dave"), cm)).To(Succeed())
+		Expect(cm.Annotations).To(HaveKeyWithValue(annotation.ChangedBy, "dave"))
+
+		// 내용을 바꾸지 않으면 작성자를 위조할 수 없음
+		cm = old.DeepCopy()
+		cm.Annotations[annotation.ChangedBy] = "mallory"
+		Expect(s.Default(updateBy("mallory"), cm)).To(Succeed())
+		Expect(cm.Annotations).To(HaveKeyWithValue(annotation.ChangedBy, "carol"))
+	})
+
+	It("records the authenticated user as the approver", func() {
+		cm := old.DeepCopy()
+		Expect(approval.Set(cm, annotation.Approval, approval.Decision{Hash: "abc", Approver: "alice"})).To(Succeed())
+		Expect(s.Default(updateBy("mallory"), cm)).To(Succeed())
+		decision, ok := approval.GetDecision(cm)
+		Expect(ok).To(BeTrue())
+		Expect(decision).To(Equal(approval.Decision{Hash: "abc", Approver: "mallory"}))
+	})
+
+	It("leaves sources that are not critical alone", func() {
+		cm := old.DeepCopy()
+		cm.Labels = nil
+		cm.Data["level"] = "debug"
+		Expect(s.Default(updateBy("dave"), cm)).To(Succeed())
+		Expect(cm.Annotations).To(HaveKeyWithValue(annotation.ChangedBy, "carol"))
+	})
+})
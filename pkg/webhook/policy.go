@@ -0,0 +1,186 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	reloaderv1alpha1 "github.com/hotkimho/reloader-server/project/api/v1alpha1"
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/policy"
+	"github.com/hotkimho/reloader-server/project/pkg/strategy"
+	"github.com/hotkimho/reloader-server/project/pkg/window"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// +kubebuilder:webhook:path=/validate-reloader-accordions-edu-v1alpha1-reloadpolicy,mutating=false,failurePolicy=fail,sideEffects=None,groups=reloader.accordions.edu,resources=reloadpolicies,verbs=create;update,versions=v1alpha1,name=vreloadpolicy.reloader.accordions.edu,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-reloader-accordions-edu-v1alpha1-clusterreloadpolicy,mutating=false,failurePolicy=fail,sideEffects=None,groups=reloader.accordions.edu,resources=clusterreloadpolicies,verbs=create;update,versions=v1alpha1,name=vclusterreloadpolicy.reloader.accordions.edu,admissionReviewVersions=v1
+
+// PolicyValidator rejects ReloadPolicies and ClusterReloadPolicies with
+// malformed rules and warns about rules selecting nothing.
+type PolicyValidator struct {
+	client client.Reader
+}
+
+var _ admission.CustomValidator = &PolicyValidator{}
+
+func (v *PolicyValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, obj)
+}
+
+func (v *PolicyValidator) ValidateUpdate(ctx context.Context, _, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, obj)
+}
+
+func (v *PolicyValidator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *PolicyValidator) validate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	spec := field.NewPath("spec")
+	switch p := obj.(type) {
+	case *reloaderv1alpha1.ReloadPolicy:
+		errs, warnings := validateRules(&p.Spec.PolicyRules, spec)
+		if len(errs) > 0 {
+			return warnings, apierrors.NewInvalid(reloaderv1alpha1.GroupVersion.WithKind("ReloadPolicy").GroupKind(), p.Name, errs)
+		}
+		unmatched, err := v.unmatchedSources(ctx, &p.Spec.PolicyRules, namespaceOf(ctx, p), spec)
+		return append(warnings, unmatched...), err
+	case *reloaderv1alpha1.ClusterReloadPolicy:
+		errs, warnings := validateRules(&p.Spec.PolicyRules, spec)
+		if _, err := metav1.LabelSelectorAsSelector(p.Spec.NamespaceSelector); err != nil {
+			errs = append(errs, field.Invalid(spec.Child("namespaceSelector"), p.Spec.NamespaceSelector, err.Error()))
+		}
+		if len(errs) > 0 {
+			return warnings, apierrors.NewInvalid(reloaderv1alpha1.GroupVersion.WithKind("ClusterReloadPolicy").GroupKind(), p.Name, errs)
+		}
+		unmatched, err := v.unmatchedNamespaces(ctx, &p.Spec, spec)
+		return append(warnings, unmatched...), err
+	}
+	return nil, nil
+}
+
+// validateRules returns the errors of rules, and warnings about targets of
+// workload kinds the reloader does not know.
+func validateRules(rules *reloaderv1alpha1.PolicyRules, spec *field.Path) (field.ErrorList, admission.Warnings) {
+	var (
+		errs     field.ErrorList
+		warnings admission.Warnings
+	)
+	for i, s := range rules.Sources {
+		p := spec.Child("sources").Index(i)
+		for j, name := range s.Names {
+			if _, err := path.Match(name, ""); err != nil {
+				errs = append(errs, field.Invalid(p.Child("names").Index(j), name, "must be a valid glob pattern"))
+			}
+		}
+		if _, err := metav1.LabelSelectorAsSelector(s.Selector); err != nil {
+			errs = append(errs, field.Invalid(p.Child("selector"), s.Selector, err.Error()))
+		}
+	}
+	for i, t := range rules.Targets {
+		p := spec.Child("targets").Index(i)
+		for j, kind := range t.Kinds {
+			if _, ok := workload.NewObject(kind); !ok {
+				warnings = append(warnings, fmt.Sprintf("%s: %s is not a workload kind known to the reloader", p.Child("kinds").Index(j), kind))
+			}
+		}
+		if _, err := metav1.LabelSelectorAsSelector(t.Selector); err != nil {
+			errs = append(errs, field.Invalid(p.Child("selector"), t.Selector, err.Error()))
+		}
+	}
+	if rules.Strategy != "" {
+		settings := &policy.Settings{}
+		settings.Add("", rules)
+		if err := strategy.Validate(settings.Annotations(nil)); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %v, targets without their own strategy annotations are restarted instead",
+				spec.Child("parameters"), err))
+		}
+	}
+	if rules.Debounce != nil && rules.Debounce.Duration < 0 {
+		errs = append(errs, field.Invalid(spec.Child("debounce"), rules.Debounce.Duration.String(), "must not be negative"))
+	}
+	if rules.Cooldown != nil && rules.Cooldown.Duration < 0 {
+		errs = append(errs, field.Invalid(spec.Child("cooldown"), rules.Cooldown.Duration.String(), "must not be negative"))
+	}
+	for i, w := range rules.Windows {
+		p := spec.Child("windows").Index(i)
+		if w.Duration.Duration <= 0 {
+			errs = append(errs, field.Invalid(p.Child("duration"), w.Duration.Duration.String(), "must be positive"))
+		}
+		if _, err := window.New([]config.MaintenanceWindow{{Schedule: w.Schedule, Duration: w.Duration}}, nil); err != nil {
+			errs = append(errs, field.Invalid(p.Child("schedule"), w.Schedule, err.Error()))
+		}
+	}
+	return errs, warnings
+}
+
+// unmatchedSources warns about the source selectors of rules that select no
+// source in namespace yet.
+func (v *PolicyValidator) unmatchedSources(ctx context.Context, rules *reloaderv1alpha1.PolicyRules, namespace string, spec *field.Path) (admission.Warnings, error) {
+	var warnings admission.Warnings
+	for i, s := range rules.Sources {
+		list := workload.NewSourceList(workload.SourceKind(s.Kind))
+		if err := v.client.List(ctx, list, client.InNamespace(namespace)); err != nil {
+			return nil, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		single := &reloaderv1alpha1.PolicyRules{Sources: []reloaderv1alpha1.SourceSelector{s}}
+		matched := false
+		for _, item := range items {
+			src := item.(client.Object)
+			if policy.MatchesSource(single, workload.SourceKind(s.Kind), src.GetName(), src.GetLabels()) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			warnings = append(warnings, fmt.Sprintf("%s: selects no existing %s in namespace %s",
+				spec.Child("sources").Index(i), s.Kind, namespace))
+		}
+	}
+	return warnings, nil
+}
+
+// unmatchedNamespaces warns when the namespace selector of a
+// ClusterReloadPolicy selects no namespace yet.
+func (v *PolicyValidator) unmatchedNamespaces(ctx context.Context, p *reloaderv1alpha1.ClusterReloadPolicySpec, spec *field.Path) (admission.Warnings, error) {
+	list := &corev1.NamespaceList{}
+	if err := v.client.List(ctx, list); err != nil {
+		return nil, err
+	}
+	for _, ns := range list.Items {
+		if policy.MatchesNamespace(p, ns.Labels) {
+			return nil, nil
+		}
+	}
+	return admission.Warnings{fmt.Sprintf("%s: selects no existing namespace", spec.Child("namespaceSelector"))}, nil
+}
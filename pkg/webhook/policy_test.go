@@ -0,0 +1,96 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	reloaderv1alpha1 "github.com/hotkimho/reloader-server/project/api/v1alpha1"
+)
+
+var _ = Describe("Policy validator", func() {
+	var (
+		ctx context.Context
+		p   *reloaderv1alpha1.ReloadPolicy
+		v   *PolicyValidator
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		p = &reloaderv1alpha1.ReloadPolicy{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		p.Spec.Sources = []reloaderv1alpha1.SourceSelector{{Kind: "ConfigMap", Names: []string{"app-*"}}}
+		v = &PolicyValidator{client: newFakeClient(
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"}},
+		)}
+	})
+
+	It("admits well formed policies", func() {
+		warnings, err := v.ValidateCreate(ctx, p)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(BeEmpty())
+	})
+
+	It("rejects malformed rules", func() {
+		p.Spec.Sources[0].Names = []string{"app-["}
+		p.Spec.Cooldown = &metav1.Duration{Duration: -time.Minute}
+		p.Spec.Windows = []reloaderv1alpha1.MaintenanceWindow{{Schedule: "nightly", Duration: metav1.Duration{}}}
+		_, err := v.ValidateCreate(ctx, p)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.(*apierrors.StatusError).ErrStatus.Details.Causes).To(HaveLen(4))
+	})
+
+	It("warns about selectors matching nothing", func() {
+		p.Spec.Sources[0].Names = []string{"db-*"}
+		p.Spec.Targets = []reloaderv1alpha1.TargetSelector{{Kinds: []string{"ReplicaSet"}}}
+		warnings, err := v.ValidateCreate(ctx, p)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(ConsistOf(
+			ContainSubstring("spec.targets[0].kinds[0]: ReplicaSet is not a workload kind"),
+			ContainSubstring("spec.sources[0]: selects no existing ConfigMap in namespace default"),
+		))
+	})
+
+	It("warns about strategies missing their parameters", func() {
+		p.Spec.Strategy = "http"
+		warnings, err := v.ValidateCreate(ctx, p)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(ConsistOf(ContainSubstring("spec.parameters: the http strategy requires")))
+
+		port := intstr.FromString("http")
+		p.Spec.Parameters = &reloaderv1alpha1.StrategyParameters{HTTPPort: &port}
+		warnings, err = v.ValidateCreate(ctx, p)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(BeEmpty())
+	})
+
+	It("warns when a cluster policy selects no namespace", func() {
+		crp := &reloaderv1alpha1.ClusterReloadPolicy{ObjectMeta: metav1.ObjectMeta{Name: "tenants"}}
+		crp.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}}
+		warnings, err := v.ValidateCreate(ctx, crp)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(ConsistOf(ContainSubstring("selects no existing namespace")))
+	})
+})
@@ -0,0 +1,274 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/policy"
+	"github.com/hotkimho/reloader-server/project/pkg/strategy"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// +kubebuilder:webhook:path=/validate--v1-configmap,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=configmaps,verbs=update,versions=v1,name=vconfigmap.reloader.accordions.edu,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate--v1-secret,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=secrets,verbs=update,versions=v1,name=vsecret.reloader.accordions.edu,admissionReviewVersions=v1
+
+// SourceValidator warns about the workloads a change to a ConfigMap or
+// Secret is going to reload, and rejects changes restarting more pods than
+// the configured threshold unless they set the override annotation. Changes
+// the controller ignores are always admitted.
+type SourceValidator struct {
+	client client.Reader
+	store  *config.Store
+}
+
+var _ admission.CustomValidator = &SourceValidator{}
+
+func (v *SourceValidator) ValidateCreate(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *SourceValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldSrc, ok := oldObj.(client.Object)
+	if !ok {
+		return nil, nil
+	}
+	src, ok := newObj.(client.Object)
+	if !ok {
+		return nil, nil
+	}
+	kind := workload.ConfigMap
+	if _, ok := src.(*corev1.Secret); ok {
+		kind = workload.Secret
+	}
+
+	if ignored, err := v.ignored(ctx, kind, oldSrc, src); err != nil || ignored {
+		return nil, err
+	}
+	radius, err := v.blastRadius(ctx, kind, oldSrc, src)
+	if err != nil {
+		return nil, err
+	}
+	warnings := radius.warnings()
+
+	limit := v.store.Get().Admission.MaxRestartedPods
+	if limit > 0 && radius.restarted.pods > limit && !overridden(oldSrc, src) {
+		resource := schema.GroupResource{Resource: strings.ToLower(string(kind)) + "s"}
+		return warnings, apierrors.NewForbidden(resource, src.GetName(), fmt.Errorf(
+			"this change would restart %d pods, more than the limit of %d; set the %s annotation to \"true\" "+
+				"in the same update to apply it anyway, after removing a leftover one",
+			radius.restarted.pods, limit, annotation.OverrideBlastRadius))
+	}
+	return warnings, nil
+}
+
+// overridden reports whether the update sets the override annotation. An
+// annotation left over from an earlier change does not admit later ones.
+func overridden(oldSrc, src client.Object) bool {
+	return src.GetAnnotations()[annotation.OverrideBlastRadius] == "true" &&
+		oldSrc.GetAnnotations()[annotation.OverrideBlastRadius] != "true"
+}
+
+// ignored reports whether the controller ignores the change from oldSrc to
+// src, so that it reloads no workload. The ignore rules of the source are
+// taken from oldSrc, the update cannot exempt itself.
+func (v *SourceValidator) ignored(ctx context.Context, kind workload.SourceKind, oldSrc, src client.Object) (bool, error) {
+	ref := workload.Reference{Kind: kind, Name: src.GetName()}
+	before, err := workload.SourceHashes(ctx, overlay{Reader: v.client, src: oldSrc}, ref, src.GetNamespace())
+	if err != nil {
+		return false, err
+	}
+	after, err := workload.SourceHashes(ctx, overlay{Reader: v.client, src: src}, ref, src.GetNamespace())
+	if err != nil {
+		return false, err
+	}
+	attribution, attributed := workload.Attribute(src, workload.ChangedKeys(before, after))
+	cfg := v.store.Get().Reload
+	_, ignored := workload.IgnoredChange(oldSrc, attribution, attributed, cfg.IgnoreManagers, cfg.IgnoreManagedBy)
+	return ignored, nil
+}
+
+func (v *SourceValidator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// reloads counts the workloads, by kind, and the pods reloaded one way.
+type reloads struct {
+	workloads map[string]int
+	pods      int
+}
+
+func (r *reloads) add(w workload.Workload, pods int) {
+	if r.workloads == nil {
+		r.workloads = map[string]int{}
+	}
+	r.workloads[w.Kind()]++
+	r.pods += pods
+}
+
+// String returns e.g. "12 Deployments, 1 StatefulSet (340 pods)".
+func (r *reloads) String() string {
+	kinds := make([]string, 0, len(r.workloads))
+	for kind := range r.workloads {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, plural(r.workloads[kind], kind))
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(parts, ", "), plural(r.pods, "pod"))
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// blastRadius is what a change to a source is going to reload.
+type blastRadius struct {
+	restarted, inPlace reloads
+}
+
+func (b *blastRadius) warnings() admission.Warnings {
+	var warnings admission.Warnings
+	if len(b.restarted.workloads) > 0 {
+		warnings = append(warnings, "this change will restart "+b.restarted.String())
+	}
+	if len(b.inPlace.workloads) > 0 {
+		warnings = append(warnings, "this change will reload "+b.inPlace.String()+" in place")
+	}
+	return warnings
+}
+
+// blastRadius returns the workloads governed by the reloader whose
+// dependency hash changes from oldSrc to src, and how they are reloaded.
+// CronJobs and dormant workloads restart no pod and are left out.
+func (v *SourceValidator) blastRadius(ctx context.Context, kind workload.SourceKind, oldSrc, src client.Object) (*blastRadius, error) {
+	before := overlay{Reader: v.client, src: oldSrc}
+	after := overlay{Reader: v.client, src: src}
+	radius := &blastRadius{}
+
+	ref := workload.Reference{Kind: kind, Name: src.GetName()}
+	workloads, err := workload.List(ctx, v.client, src.GetNamespace(), client.MatchingFields{workload.SourceIndex: ref.String()})
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range workloads {
+		if w.Kind() == workload.CronJob || !workload.DependsOn(w, kind, src.GetName()) {
+			continue
+		}
+		if _, dormant := workload.Dormant(w); dormant {
+			continue
+		}
+		settings, governed, err := v.governs(ctx, w, kind, src)
+		if err != nil {
+			return nil, err
+		}
+		if !governed {
+			continue
+		}
+		changed, err := hashChanges(ctx, before, after, w, workload.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			continue
+		}
+		pods, err := workload.Pods(ctx, v.client, w)
+		if err != nil {
+			return nil, err
+		}
+
+		annotations := settings.Annotations(w.Object().GetAnnotations())
+		restart := strategy.Name(annotations) == strategy.Restart || strategy.Validate(annotations) != nil
+		if !restart {
+			// env, subPath 로 소비되는 key 가 바뀌면 strategy 와 관계없이 restart
+			if restart, err = hashChanges(ctx, before, after, w, workload.StaticHash); err != nil {
+				return nil, err
+			}
+		}
+		if restart {
+			radius.restarted.add(w, len(pods))
+		} else {
+			radius.inPlace.add(w, len(pods))
+		}
+	}
+	return radius, nil
+}
+
+// governs reports whether changes to src reload w, and returns the settings
+// of the policies selecting w.
+func (v *SourceValidator) governs(ctx context.Context, w workload.Workload, kind workload.SourceKind, src client.Object) (*policy.Settings, bool, error) {
+	settings, err := policy.Select(ctx, v.client, w)
+	if err != nil {
+		return nil, false, err
+	}
+	if w.Object().GetAnnotations()[annotation.Auto] == "true" {
+		return settings, true, nil
+	}
+	return settings, settings.Governs(kind, src.GetName(), src.GetLabels()), nil
+}
+
+func hashChanges(ctx context.Context, before, after client.Reader, w workload.Workload,
+	hash func(context.Context, client.Reader, workload.Workload) (string, error)) (bool, error) {
+	old, err := hash(ctx, before, w)
+	if err != nil {
+		return false, err
+	}
+	current, err := hash(ctx, after, w)
+	return old != current, err
+}
+
+// overlay reads src instead of the stored object, to hash the workloads as
+// they are before or after the change under admission.
+type overlay struct {
+	client.Reader
+	src client.Object
+}
+
+func (o overlay) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if key == client.ObjectKeyFromObject(o.src) {
+		switch out := obj.(type) {
+		case *corev1.ConfigMap:
+			if cm, ok := o.src.(*corev1.ConfigMap); ok {
+				cm.DeepCopyInto(out)
+				return nil
+			}
+		case *corev1.Secret:
+			if secret, ok := o.src.(*corev1.Secret); ok {
+				secret.DeepCopyInto(out)
+				return nil
+			}
+		}
+	}
+	return o.Reader.Get(ctx, key, obj, opts...)
+}
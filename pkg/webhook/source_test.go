@@ -0,0 +1,150 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+)
+
+var _ = Describe("Source validator", func() {
+	var (
+		ctx   context.Context
+		cm    *corev1.ConfigMap
+		v     *SourceValidator
+		store *config.Store
+	)
+
+	newDeployment := func(name string, annotations map[string]string, env bool) *appsv1.Deployment {
+		d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: annotations}}
+		d.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}}
+		c := corev1.Container{Name: "app"}
+		if env {
+			c.EnvFrom = []corev1.EnvFromSource{
+				{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}}},
+			}
+		} else {
+			c.VolumeMounts = []corev1.VolumeMount{{Name: "config", MountPath: "/etc/app"}}
+			d.Spec.Template.Spec.Volumes = []corev1.Volume{{Name: "config", VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}},
+			}}}
+		}
+		d.Spec.Template.Spec.Containers = []corev1.Container{c}
+		return d
+	}
+	newPods := func(app string, n int) []client.Object {
+		var pods []client.Object
+		for i := 0; i < n; i++ {
+			pods = append(pods, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("%s-%d", app, i), Namespace: "default", Labels: map[string]string{"app": app},
+			}})
+		}
+		return pods
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"}, Data: map[string]string{"a": "1"}}
+		store = config.NewStore(config.NewConfig())
+
+		objs := []client.Object{
+			cm,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+			newDeployment("web", map[string]string{annotation.Auto: "true"}, true),
+			newDeployment("api", map[string]string{annotation.Auto: "true"}, true),
+			newDeployment("proxy", map[string]string{annotation.Auto: "true", annotation.Strategy: "signal"}, false),
+			newDeployment("batch", nil, true),
+		}
+		objs = append(objs, newPods("web", 3)...)
+		objs = append(objs, newPods("api", 2)...)
+		objs = append(objs, newPods("proxy", 1)...)
+		objs = append(objs, newPods("batch", 4)...)
+		v = &SourceValidator{client: newFakeClient(objs...), store: store}
+	})
+
+	It("warns about the workloads a change reloads", func() {
+		changed := cm.DeepCopy()
+		changed.Data["a"] = "2"
+		warnings, err := v.ValidateUpdate(ctx, cm, changed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(ConsistOf(
+			"this change will restart 2 Deployments (5 pods)",
+			"this change will reload 1 Deployment (1 pod) in place",
+		))
+	})
+
+	It("does not warn about changes leaving the content alone", func() {
+		changed := cm.DeepCopy()
+		changed.Labels = map[string]string{"team": "web"}
+		warnings, err := v.ValidateUpdate(ctx, cm, changed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(BeEmpty())
+	})
+
+	It("rejects changes restarting more pods than the threshold unless overridden", func() {
+		store.Get().Admission.MaxRestartedPods = 4
+		changed := cm.DeepCopy()
+		changed.Data["a"] = "2"
+		warnings, err := v.ValidateUpdate(ctx, cm, changed)
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("would restart 5 pods, more than the limit of 4"))
+		Expect(warnings).NotTo(BeEmpty())
+
+		changed.Annotations = map[string]string{annotation.OverrideBlastRadius: "true"}
+		_, err = v.ValidateUpdate(ctx, cm, changed)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("does not honor an override left over from an earlier change", func() {
+		store.Get().Admission.MaxRestartedPods = 4
+		cm.Annotations = map[string]string{annotation.OverrideBlastRadius: "true"}
+		changed := cm.DeepCopy()
+		changed.Data["a"] = "2"
+		_, err := v.ValidateUpdate(ctx, cm, changed)
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+	})
+
+	It("admits changes the controller ignores", func() {
+		store.Get().Admission.MaxRestartedPods = 4
+		store.Get().Reload.IgnoreManagers = []string{"*-leader-election"}
+		changed := cm.DeepCopy()
+		changed.Data["a"] = "2"
+		changed.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kube-leader-election", Operation: metav1.ManagedFieldsOperationUpdate,
+			FieldsType: "FieldsV1", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:a":{}}}`)}}}
+		warnings, err := v.ValidateUpdate(ctx, cm, changed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(BeEmpty())
+
+		// update 가 스스로 무시 대상이 될 수는 없음
+		changed.ManagedFields[0].Manager = "kubectl-edit"
+		changed.Annotations = map[string]string{annotation.IgnoreManagers: "kubectl-*"}
+		_, err = v.ValidateUpdate(ctx, cm, changed)
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+	})
+})
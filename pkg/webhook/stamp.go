@@ -0,0 +1,131 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/policy"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// +kubebuilder:webhook:path=/mutate-apps-v1-deployment,mutating=true,failurePolicy=ignore,sideEffects=None,groups=apps,resources=deployments,verbs=create;update,versions=v1,name=mdeployment.reloader.accordions.edu,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-apps-v1-statefulset,mutating=true,failurePolicy=ignore,sideEffects=None,groups=apps,resources=statefulsets,verbs=create;update,versions=v1,name=mstatefulset.reloader.accordions.edu,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-apps-v1-daemonset,mutating=true,failurePolicy=ignore,sideEffects=None,groups=apps,resources=daemonsets,verbs=create;update,versions=v1,name=mdaemonset.reloader.accordions.edu,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-batch-v1-cronjob,mutating=true,failurePolicy=ignore,sideEffects=None,groups=batch,resources=cronjobs,verbs=create;update,versions=v1,name=mcronjob.reloader.accordions.edu,admissionReviewVersions=v1
+
+// HashStamper stamps the current dependency hash on the pod template of the
+// reloaded workloads being rolled out, so the first rollout of a new or
+// changed workload already runs the current configuration and the controller
+// does not roll it a second time. Workloads whose pod spec does not change
+// are left alone: stamping them would roll them past the reload gates.
+type HashStamper struct {
+	client client.Reader
+}
+
+var _ admission.CustomDefaulter = &HashStamper{}
+
+func (s *HashStamper) Default(ctx context.Context, obj runtime.Object) error {
+	cobj, ok := obj.(client.Object)
+	if !ok {
+		return nil
+	}
+	w, ok := workload.New(cobj)
+	if !ok {
+		return nil
+	}
+	if cobj.GetNamespace() == "" {
+		cobj.SetNamespace(namespaceOf(ctx, cobj))
+	}
+	// 실패해도 controller 가 나중에 reload 하므로 workload 를 막지 않음
+	if err := s.stamp(ctx, w); err != nil {
+		log.FromContext(ctx).Error(err, "unable to stamp dependency hash", "workload", w.Kind()+"/"+cobj.GetName())
+	}
+	return nil
+}
+
+// stamp sets the current dependency hash on w when it is governed by the
+// reloader and rolls out.
+func (s *HashStamper) stamp(ctx context.Context, w workload.Workload) error {
+	rolling, err := rollsOut(ctx, w)
+	if err != nil || !rolling {
+		return err
+	}
+	governed, err := s.governed(ctx, w)
+	if err != nil || !governed {
+		return err
+	}
+
+	hash, err := workload.Hash(ctx, s.client, w)
+	if err != nil {
+		return err
+	}
+	static, err := workload.StaticHash(ctx, s.client, w)
+	if err != nil {
+		return err
+	}
+	obj := w.Object()
+	w.SetTemplateAnnotation(annotation.ConfigHash, hash)
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[annotation.ConfigHash] = hash
+	annotations[annotation.StaticHash] = static
+	obj.SetAnnotations(annotations)
+
+	log.FromContext(ctx).V(1).Info("stamped dependency hash at admission", "workload", w.Kind()+"/"+obj.GetName(), "hash", hash)
+	return nil
+}
+
+// governed reports whether w opted in to reloads or a policy selects it.
+func (s *HashStamper) governed(ctx context.Context, w workload.Workload) (bool, error) {
+	if w.Object().GetAnnotations()[annotation.Auto] == "true" {
+		return true, nil
+	}
+	settings, err := policy.Select(ctx, s.client, w)
+	return !settings.Empty(), err
+}
+
+// rollsOut reports whether admitting w starts a rollout anyway: it is being
+// created, or its pod spec changes.
+func rollsOut(ctx context.Context, w workload.Workload) (bool, error) {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil || req.Operation != admissionv1.Update {
+		return true, nil
+	}
+	old := reflect.New(reflect.TypeOf(w.Object()).Elem()).Interface().(client.Object)
+	if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+		return false, fmt.Errorf("decoding old object: %w", err)
+	}
+	prev, ok := workload.New(old)
+	if !ok {
+		return false, nil
+	}
+	return !equality.Semantic.DeepEqual(prev.PodSpec(), w.PodSpec()), nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	reloaderv1alpha1 "github.com/hotkimho/reloader-server/project/api/v1alpha1"
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+var _ = Describe("Hash stamper", func() {
+	var (
+		ctx context.Context
+		d   *appsv1.Deployment
+		s   *HashStamper
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		d = &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name: "web", Namespace: "default", Annotations: map[string]string{annotation.Auto: "true"},
+		}}
+		d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "web", Image: "web:1", EnvFrom: []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}}},
+		}}}
+		s = &HashStamper{client: newFakeClient(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"}, Data: map[string]string{"a": "b"}},
+		)}
+	})
+
+	currentHash := func() string {
+		w, _ := workload.New(d)
+		hash, err := workload.Hash(ctx, s.client, w)
+		Expect(err).NotTo(HaveOccurred())
+		return hash
+	}
+	updating := func(old *appsv1.Deployment) context.Context {
+		raw, err := json.Marshal(old)
+		Expect(err).NotTo(HaveOccurred())
+		return admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Update,
+			OldObject: runtime.RawExtension{Raw: raw},
+		}})
+	}
+
+	It("stamps the current hash on created workloads", func() {
+		Expect(s.Default(ctx, d)).To(Succeed())
+		hash := currentHash()
+		Expect(d.Spec.Template.Annotations).To(HaveKeyWithValue(annotation.ConfigHash, hash))
+		Expect(d.Annotations).To(HaveKeyWithValue(annotation.ConfigHash, hash))
+		Expect(d.Annotations).To(HaveKey(annotation.StaticHash))
+	})
+
+	It("leaves workloads the reloader does not govern alone", func() {
+		d.Annotations = nil
+		Expect(s.Default(ctx, d)).To(Succeed())
+		Expect(d.Spec.Template.Annotations).To(BeNil())
+		Expect(d.Annotations).To(BeNil())
+	})
+
+	It("stamps workloads selected by a policy", func() {
+		d.Annotations = nil
+		p := &reloaderv1alpha1.ReloadPolicy{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		s.client = newFakeClient(p, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"}})
+		Expect(s.Default(ctx, d)).To(Succeed())
+		Expect(d.Spec.Template.Annotations).To(HaveKeyWithValue(annotation.ConfigHash, currentHash()))
+	})
+
+	It("only stamps updates changing the pod spec", func() {
+		old := d.DeepCopy()
+		Expect(s.Default(updating(old), d)).To(Succeed())
+		Expect(d.Spec.Template.Annotations).NotTo(HaveKey(annotation.ConfigHash))
+
+		d.Spec.Template.Spec.Containers[0].Image = "web:2"
+		Expect(s.Default(updating(old), d)).To(Succeed())
+		Expect(d.Spec.Template.Annotations).To(HaveKeyWithValue(annotation.ConfigHash, currentHash()))
+	})
+})
@@ -0,0 +1,57 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook implements the admission webhooks of the reloader.
+package webhook
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	reloaderv1alpha1 "github.com/hotkimho/reloader-server/project/api/v1alpha1"
+	"github.com/hotkimho/reloader-server/project/internal/config"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// SetupWithManager registers the mutating and validating webhooks of the
+// workload kinds and of the ConfigMaps and Secrets, and the validating
+// webhooks of the reload policies. Generic workload kinds and the workload
+// indexes must be registered before.
+func SetupWithManager(mgr ctrl.Manager, store *config.Store) error {
+	stamper := &HashStamper{client: mgr.GetClient()}
+	workloads := &WorkloadValidator{client: mgr.GetClient()}
+	for _, obj := range workload.Objects() {
+		if err := ctrl.NewWebhookManagedBy(mgr).For(obj).WithDefaulter(stamper).WithValidator(workloads).Complete(); err != nil {
+			return err
+		}
+	}
+
+	policies := &PolicyValidator{client: mgr.GetClient()}
+	for _, obj := range []client.Object{&reloaderv1alpha1.ReloadPolicy{}, &reloaderv1alpha1.ClusterReloadPolicy{}} {
+		if err := ctrl.NewWebhookManagedBy(mgr).For(obj).WithValidator(policies).Complete(); err != nil {
+			return err
+		}
+	}
+
+	sources := &SourceValidator{client: mgr.GetClient(), store: store}
+	for _, obj := range []client.Object{&corev1.ConfigMap{}, &corev1.Secret{}} {
+		if err := ctrl.NewWebhookManagedBy(mgr).For(obj).WithDefaulter(&ApprovalStamper{}).WithValidator(sources).Complete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+	"github.com/hotkimho/reloader-server/project/pkg/strategy"
+	"github.com/hotkimho/reloader-server/project/pkg/window"
+	"github.com/hotkimho/reloader-server/project/pkg/workload"
+)
+
+// +kubebuilder:webhook:path=/validate-apps-v1-deployment,mutating=false,failurePolicy=ignore,sideEffects=None,groups=apps,resources=deployments,verbs=create;update,versions=v1,name=vdeployment.reloader.accordions.edu,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-apps-v1-statefulset,mutating=false,failurePolicy=ignore,sideEffects=None,groups=apps,resources=statefulsets,verbs=create;update,versions=v1,name=vstatefulset.reloader.accordions.edu,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-apps-v1-daemonset,mutating=false,failurePolicy=ignore,sideEffects=None,groups=apps,resources=daemonsets,verbs=create;update,versions=v1,name=vdaemonset.reloader.accordions.edu,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-batch-v1-cronjob,mutating=false,failurePolicy=ignore,sideEffects=None,groups=batch,resources=cronjobs,verbs=create;update,versions=v1,name=vcronjob.reloader.accordions.edu,admissionReviewVersions=v1
+
+// WorkloadValidator rejects workloads with malformed reloader annotations
+// and warns about the sources of opted in workloads that do not exist.
+// Workloads are admitted when the webhook is unavailable, so the reloader
+// never blocks deployments.
+type WorkloadValidator struct {
+	client client.Reader
+}
+
+var _ admission.CustomValidator = &WorkloadValidator{}
+
+func (v *WorkloadValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, nil, obj)
+}
+
+func (v *WorkloadValidator) ValidateUpdate(ctx context.Context, oldObj, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, oldObj, obj)
+}
+
+func (v *WorkloadValidator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate rejects the malformed annotations of obj. On updates, annotations
+// that were already malformed on oldObj only warn, so that unrelated changes,
+// e.g. a scale, are not blocked by them.
+func (v *WorkloadValidator) validate(ctx context.Context, oldObj, obj runtime.Object) (admission.Warnings, error) {
+	cobj, ok := obj.(client.Object)
+	if !ok {
+		return nil, nil
+	}
+	w, ok := workload.New(cobj)
+	if !ok {
+		return nil, nil
+	}
+
+	var warnings admission.Warnings
+	path := field.NewPath("metadata", "annotations")
+	errs := validateAnnotations(cobj.GetAnnotations(), path)
+	if old, ok := oldObj.(client.Object); ok && len(errs) > 0 {
+		var introduced field.ErrorList
+		existing := validateAnnotations(old.GetAnnotations(), path)
+		for _, err := range errs {
+			if containsError(existing, err) {
+				warnings = append(warnings, err.Error())
+				continue
+			}
+			introduced = append(introduced, err)
+		}
+		errs = introduced
+	}
+	if len(errs) > 0 {
+		gvk := cobj.GetObjectKind().GroupVersionKind()
+		return nil, apierrors.NewInvalid(schema.GroupKind{Group: gvk.Group, Kind: w.Kind()}, cobj.GetName(), errs)
+	}
+	if cobj.GetAnnotations()[annotation.Auto] != "true" {
+		return warnings, nil
+	}
+	missing, err := v.missingSources(ctx, w, namespaceOf(ctx, cobj))
+	return append(warnings, missing...), err
+}
+
+// containsError reports whether errs holds the same error as err.
+func containsError(errs field.ErrorList, err *field.Error) bool {
+	for _, e := range errs {
+		if e.Type == err.Type && e.Field == err.Field && e.Detail == err.Detail && e.BadValue == err.BadValue {
+			return true
+		}
+	}
+	return false
+}
+
+// missingSources warns about the required sources of w that do not exist.
+func (v *WorkloadValidator) missingSources(ctx context.Context, w workload.Workload, namespace string) (admission.Warnings, error) {
+	var warnings admission.Warnings
+	for _, ref := range workload.References(w) {
+		if ref.Optional {
+			continue
+		}
+		src := workload.NewSource(ref.Kind)
+		err := v.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, src)
+		switch {
+		case apierrors.IsNotFound(err):
+			warnings = append(warnings, fmt.Sprintf("%s %s/%s does not exist, pods will not start until it is created",
+				ref.Kind, namespace, ref.Name))
+		case err != nil:
+			return nil, err
+		}
+	}
+	return warnings, nil
+}
+
+// validateAnnotations returns the errors of the reloader annotations of a workload.
+func validateAnnotations(annotations map[string]string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for _, key := range []string{annotation.Auto, annotation.IgnoreDelete, annotation.Approve, annotation.RecreatePendingJobs} {
+		if v, ok := annotations[key]; ok && v != "true" && v != "false" {
+			errs = append(errs, field.Invalid(path.Key(key), v, `must be "true" or "false"`))
+		}
+	}
+	for _, key := range []string{annotation.Cooldown, annotation.SyncDelay, annotation.RestartJitter} {
+		if v, ok := annotations[key]; ok {
+			if d, err := time.ParseDuration(v); err != nil || d < 0 {
+				errs = append(errs, field.Invalid(path.Key(key), v, "must be a non-negative duration, e.g. 5m"))
+			}
+		}
+	}
+	for _, key := range []string{annotation.ConfigMapKeys, annotation.SecretKeys} {
+		if v, ok := annotations[key]; ok {
+			for _, entry := range strings.Split(v, ",") {
+				name, k, found := strings.Cut(strings.TrimSpace(entry), ":")
+				if !found || name == "" || k == "" {
+					errs = append(errs, field.Invalid(path.Key(key), v, `must be a comma separated list of "name:key"`))
+					break
+				}
+			}
+		}
+	}
+	if v, ok := annotations[annotation.RestartSchedule]; ok {
+		if _, err := window.NewRecurrence(v, 0); err != nil {
+			errs = append(errs, field.Invalid(path.Key(annotation.RestartSchedule), v, err.Error()))
+		}
+	}
+	if err := strategy.Validate(annotations); err != nil {
+		errs = append(errs, field.Invalid(path.Key(annotation.Strategy), strategy.Name(annotations), err.Error()))
+	}
+	return errs
+}
+
+// namespaceOf returns the namespace of obj, which is only set on the request
+// when it is created without one.
+func namespaceOf(ctx context.Context, obj client.Object) string {
+	if obj.GetNamespace() != "" {
+		return obj.GetNamespace()
+	}
+	if req, err := admission.RequestFromContext(ctx); err == nil {
+		return req.Namespace
+	}
+	return ""
+}
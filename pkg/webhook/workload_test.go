@@ -0,0 +1,110 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+)
+
+var _ = Describe("Workload validator", func() {
+	var (
+		ctx context.Context
+		d   *appsv1.Deployment
+		v   *WorkloadValidator
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		d = &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "web", EnvFrom: []corev1.EnvFromSource{
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}}},
+		}}}
+		v = &WorkloadValidator{client: newFakeClient()}
+	})
+
+	It("admits workloads without reloader annotations", func() {
+		warnings, err := v.ValidateCreate(ctx, d)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(BeEmpty())
+	})
+
+	It("rejects malformed annotations", func() {
+		d.Annotations = map[string]string{
+			annotation.Auto:            "yes",
+			annotation.Cooldown:        "-1m",
+			annotation.ConfigMapKeys:   "app-config",
+			annotation.RestartSchedule: "every day",
+		}
+		_, err := v.ValidateCreate(ctx, d)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+
+		status := err.(*apierrors.StatusError).ErrStatus
+		fields := []string{}
+		for _, c := range status.Details.Causes {
+			fields = append(fields, c.Field)
+		}
+		Expect(fields).To(ConsistOf(
+			"metadata.annotations["+annotation.Auto+"]",
+			"metadata.annotations["+annotation.Cooldown+"]",
+			"metadata.annotations["+annotation.ConfigMapKeys+"]",
+			"metadata.annotations["+annotation.RestartSchedule+"]",
+		))
+	})
+
+	It("rejects unknown strategies", func() {
+		old := d.DeepCopy()
+		d.Annotations = map[string]string{annotation.Strategy: "explode"}
+		_, err := v.ValidateUpdate(ctx, old, d)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+	})
+
+	It("only warns about malformed annotations the update did not change", func() {
+		d.Annotations = map[string]string{annotation.Cooldown: "soon"}
+		old := d.DeepCopy()
+		d.Spec.Replicas = ptr.To(int32(3))
+		warnings, err := v.ValidateUpdate(ctx, old, d)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(ConsistOf(ContainSubstring(annotation.Cooldown)))
+
+		d.Annotations[annotation.Cooldown] = "later"
+		_, err = v.ValidateUpdate(ctx, old, d)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+	})
+
+	It("warns about missing sources of opted in workloads", func() {
+		d.Annotations = map[string]string{annotation.Auto: "true"}
+		warnings, err := v.ValidateCreate(ctx, d)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(ConsistOf(ContainSubstring("ConfigMap default/app-config does not exist")))
+
+		v.client = newFakeClient(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"}})
+		warnings, err = v.ValidateCreate(ctx, d)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(BeEmpty())
+	})
+})
@@ -0,0 +1,56 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package window
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Recurrence is a periodic schedule shifted by a fixed jitter offset.
+type Recurrence struct {
+	schedule cron.Schedule
+	offset   time.Duration
+}
+
+// NewRecurrence parses spec, either a cron expression, e.g. "0 3 * * *", or
+// an interval, e.g. "24h". Every occurrence is delayed by offset.
+func NewRecurrence(spec string, offset time.Duration) (*Recurrence, error) {
+	if d, err := time.ParseDuration(spec); err == nil {
+		if d < time.Minute {
+			return nil, fmt.Errorf("interval %q must be at least a minute", spec)
+		}
+		return &Recurrence{schedule: cron.Every(d), offset: offset}, nil
+	}
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	return &Recurrence{schedule: schedule, offset: offset}, nil
+}
+
+// Next returns the occurrence following last, or following now when there
+// was none yet.
+func (r *Recurrence) Next(last, now time.Time) time.Time {
+	if last.IsZero() {
+		return r.schedule.Next(now).Add(r.offset)
+	}
+	// last 는 offset 만큼 밀린 시간이므로 원래 시간 기준으로 다음 시간 계산
+	return r.schedule.Next(last.Add(-r.offset)).Add(r.offset)
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package window
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Recurrence", func() {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 6, day, hour, minute, 0, 0, time.Local)
+	}
+
+	It("follows a cron schedule shifted by the offset", func() {
+		r, err := NewRecurrence("0 3 * * *", 7*time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+
+		first := r.Next(time.Time{}, at(3, 13, 0))
+		Expect(first).To(Equal(at(4, 3, 7)))
+		Expect(r.Next(first, first)).To(Equal(at(5, 3, 7)))
+	})
+
+	It("follows an interval from the last occurrence", func() {
+		r, err := NewRecurrence("24h", 7*time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+
+		first := r.Next(time.Time{}, at(3, 13, 0))
+		Expect(first).To(Equal(at(4, 13, 7)))
+		Expect(r.Next(first, first)).To(Equal(at(5, 13, 7)))
+	})
+
+	It("rejects invalid schedules", func() {
+		_, err := NewRecurrence("every day", 0)
+		Expect(err).To(HaveOccurred())
+		_, err = NewRecurrence("10s", 0)
+		Expect(err).To(HaveOccurred())
+	})
+})
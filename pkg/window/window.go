@@ -0,0 +1,121 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package window evaluates maintenance windows and change freezes.
+package window
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+)
+
+// maxSteps bounds the search for the next allowed time.
+const maxSteps = 1000
+
+// Schedule tells when reloads are allowed.
+type Schedule struct {
+	windows []window
+	freezes []config.FreezePeriod
+}
+
+type window struct {
+	schedule cron.Schedule
+	duration time.Duration
+}
+
+// New parses the allowed windows and freezes. Without windows, any time
+// outside a freeze is allowed.
+func New(windows []config.MaintenanceWindow, freezes []config.FreezePeriod) (*Schedule, error) {
+	s := &Schedule{freezes: freezes}
+	for _, w := range windows {
+		schedule, err := cron.ParseStandard(w.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid window schedule %q: %w", w.Schedule, err)
+		}
+		if w.Duration.Duration <= 0 {
+			return nil, fmt.Errorf("window %q must have a positive duration", w.Schedule)
+		}
+		s.windows = append(s.windows, window{schedule: schedule, duration: w.Duration.Duration})
+	}
+	for _, f := range freezes {
+		if !f.End.After(f.Start.Time) {
+			return nil, fmt.Errorf("freeze ending %s must end after its start", f.End.Format(time.RFC3339))
+		}
+	}
+	return s, nil
+}
+
+// Allowed reports whether a reload may run at t.
+func (s *Schedule) Allowed(t time.Time) bool {
+	return s.frozenUntil(t).IsZero() && s.open(t)
+}
+
+// Next returns the earliest time at or after t when a reload may run. It
+// returns the zero time when no such time is found, e.g. when every window
+// falls into a freeze.
+func (s *Schedule) Next(t time.Time) time.Time {
+	for i := 0; i < maxSteps; i++ {
+		if end := s.frozenUntil(t); !end.IsZero() {
+			t = end
+			continue
+		}
+		if !s.open(t) {
+			t = s.nextOpening(t)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// open reports whether t falls into one of the windows.
+func (s *Schedule) open(t time.Time) bool {
+	if len(s.windows) == 0 {
+		return true
+	}
+	for _, w := range s.windows {
+		// 가장 최근에 열린 시간이 t - duration 이후라면 아직 열려 있음
+		if opened := w.schedule.Next(t.Add(-w.duration)); !opened.After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schedule) nextOpening(t time.Time) time.Time {
+	var next time.Time
+	for _, w := range s.windows {
+		if n := w.schedule.Next(t); next.IsZero() || n.Before(next) {
+			next = n
+		}
+	}
+	return next
+}
+
+// frozenUntil returns the end of the freeze covering t, or the zero time.
+func (s *Schedule) frozenUntil(t time.Time) time.Time {
+	var end time.Time
+	for _, f := range s.freezes {
+		if !t.Before(f.Start.Time) && t.Before(f.End.Time) && f.End.After(end) {
+			end = f.End.Time
+		}
+	}
+	return end
+}
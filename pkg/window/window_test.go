@@ -0,0 +1,79 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package window
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/hotkimho/reloader-server/project/internal/config"
+)
+
+var _ = Describe("Schedule", func() {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 6, 3, hour, minute, 0, 0, time.UTC)
+	}
+	nightly := []config.MaintenanceWindow{{
+		Schedule: "0 2 * * *",
+		Duration: metav1.Duration{Duration: 2 * time.Hour},
+	}}
+
+	It("allows any time without windows or freezes", func() {
+		s, err := New(nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Allowed(at(13, 0))).To(BeTrue())
+		Expect(s.Next(at(13, 0))).To(Equal(at(13, 0)))
+	})
+
+	It("allows reloads inside a window only", func() {
+		s, err := New(nightly, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Allowed(at(2, 0))).To(BeTrue())
+		Expect(s.Allowed(at(3, 59))).To(BeTrue())
+		Expect(s.Allowed(at(4, 0))).To(BeFalse())
+		Expect(s.Next(at(13, 0))).To(Equal(at(2, 0).AddDate(0, 0, 1)))
+	})
+
+	It("skips windows falling into a freeze", func() {
+		freezes := []config.FreezePeriod{{
+			Start: metav1.NewTime(at(0, 0)),
+			End:   metav1.NewTime(at(0, 0).AddDate(0, 0, 2)),
+		}}
+		s, err := New(nightly, freezes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Allowed(at(2, 30))).To(BeFalse())
+		Expect(s.Next(at(2, 30))).To(Equal(at(2, 0).AddDate(0, 0, 2)))
+	})
+
+	It("resumes right after a freeze without windows", func() {
+		freezes := []config.FreezePeriod{{Start: metav1.NewTime(at(10, 0)), End: metav1.NewTime(at(12, 0))}}
+		s, err := New(nil, freezes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.Next(at(11, 0))).To(Equal(at(12, 0)))
+	})
+
+	It("rejects malformed windows", func() {
+		_, err := New([]config.MaintenanceWindow{{Schedule: "every night", Duration: metav1.Duration{Duration: time.Hour}}}, nil)
+		Expect(err).To(HaveOccurred())
+		_, err = New([]config.MaintenanceWindow{{Schedule: "0 2 * * *"}}, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,98 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"encoding/json"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// dataFields are the fields holding the content of a ConfigMap or Secret.
+var dataFields = []string{"f:data", "f:binaryData"}
+
+// Attribution is who last changed the content of a source, and when.
+type Attribution struct {
+	// Manager is the field manager, e.g. kubectl-edit or helm.
+	Manager string
+	// Operation is Apply or Update.
+	Operation string
+	// Time is when the manager last changed its fields, zero if unknown.
+	Time time.Time
+}
+
+// Attribute returns the field manager responsible for the content of src,
+// from its managed fields: the latest entry owning one of the given keys,
+// or, if none does, the latest entry owning any data. It returns false
+// when no entry covers the data, e.g. when the source has no data.
+func Attribute(src client.Object, keys []string) (Attribution, bool) {
+	var best, fallback *metav1.ManagedFieldsEntry
+	entries := src.GetManagedFields()
+	for i := range entries {
+		e := &entries[i]
+		if e.Subresource != "" || e.FieldsV1 == nil {
+			continue
+		}
+		var fields map[string]map[string]json.RawMessage
+		if json.Unmarshal(e.FieldsV1.Raw, &fields) != nil {
+			continue
+		}
+		covers, owns := false, false
+		for _, f := range dataFields {
+			data, ok := fields[f]
+			if !ok {
+				continue
+			}
+			covers = true
+			for _, k := range keys {
+				if _, ok := data["f:"+k]; ok {
+					owns = true
+				}
+			}
+		}
+		if owns && later(e, best) {
+			best = e
+		}
+		if covers && later(e, fallback) {
+			fallback = e
+		}
+	}
+	if best == nil {
+		best = fallback
+	}
+	if best == nil {
+		return Attribution{}, false
+	}
+	a := Attribution{Manager: best.Manager, Operation: string(best.Operation)}
+	if best.Time != nil {
+		a.Time = best.Time.Time
+	}
+	return a, true
+}
+
+// later reports whether e changed its fields after other.
+func later(e, other *metav1.ManagedFieldsEntry) bool {
+	if other == nil {
+		return true
+	}
+	if e.Time == nil {
+		return false
+	}
+	return other.Time == nil || e.Time.After(other.Time.Time)
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Attribute", func() {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	entry := func(manager string, age time.Duration, fields string) metav1.ManagedFieldsEntry {
+		return metav1.ManagedFieldsEntry{
+			Manager:    manager,
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			Time:       &metav1.Time{Time: now.Add(-age)},
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(fields)},
+		}
+	}
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{ManagedFields: []metav1.ManagedFieldsEntry{
+		entry("helm", 2*time.Hour, `{"f:data":{".":{},"f:a":{},"f:b":{}}}`),
+		entry("kubectl-edit", time.Hour, `{"f:data":{"f:c":{}}}`),
+		entry("kubectl-label", time.Minute, `{"f:metadata":{"f:labels":{"f:team":{}}}}`),
+	}}}
+
+	It("attributes changed keys to the manager owning them", func() {
+		a, ok := Attribute(cm, []string{"b"})
+		Expect(ok).To(BeTrue())
+		Expect(a).To(Equal(Attribution{Manager: "helm", Operation: "Update", Time: now.Add(-2 * time.Hour)}))
+	})
+
+	It("falls back to the latest manager of the data", func() {
+		a, ok := Attribute(cm, nil)
+		Expect(ok).To(BeTrue())
+		Expect(a.Manager).To(Equal("kubectl-edit"))
+
+		a, _ = Attribute(cm, []string{"removed"})
+		Expect(a.Manager).To(Equal("kubectl-edit"))
+	})
+
+	It("knows nobody when no entry covers the data", func() {
+		_, ok := Attribute(&corev1.ConfigMap{}, nil)
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1,118 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// certificateKeys are the keys of a TLS Secret holding PEM certificates.
+var certificateKeys = []string{corev1.TLSCertKey, "ca.crt"}
+
+// CertificateHashes returns the hashes of the certificates and private key of
+// a TLS Secret by identity: certificates by their sorted SHA-256 fingerprints
+// and the private key by the fingerprint of its public key. Re-encoding or
+// reordering the PEM blocks keeps the hashes. Keys that do not parse are left out.
+func CertificateHashes(data map[string][]byte) map[string]string {
+	hashes := map[string]string{}
+	for _, k := range certificateKeys {
+		certs := parseCertificates(data[k])
+		if len(certs) == 0 {
+			continue
+		}
+		fingerprints := make([]string, 0, len(certs))
+		for _, cert := range certs {
+			sum := sha256.Sum256(cert.Raw)
+			fingerprints = append(fingerprints, hex.EncodeToString(sum[:]))
+		}
+		sort.Strings(fingerprints)
+		hashes[k] = hashString("certificates:" + strings.Join(fingerprints, ","))
+	}
+	if pub, ok := publicKey(data[corev1.TLSPrivateKeyKey]); ok {
+		hashes[corev1.TLSPrivateKeyKey] = hashString("key:" + string(pub))
+	}
+	return hashes
+}
+
+// CertificateExpiry returns the earliest expiry of the certificates in the
+// tls.crt key of data, or false if it holds none.
+func CertificateExpiry(data map[string][]byte) (time.Time, bool) {
+	var expiry time.Time
+	for _, cert := range parseCertificates(data[corev1.TLSCertKey]) {
+		if expiry.IsZero() || cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+	}
+	return expiry, !expiry.IsZero()
+}
+
+func parseCertificates(value []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, value = pem.Decode(value)
+		if block == nil {
+			return certs
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// publicKey returns the DER encoded public key of the PEM private key in value.
+func publicKey(value []byte) ([]byte, bool) {
+	block, _ := pem.Decode(value)
+	if block == nil {
+		return nil, false
+	}
+	var key interface{ Public() crypto.PublicKey }
+	if k, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		key, _ = k.(interface{ Public() crypto.PublicKey })
+	} else if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		key = k
+	} else if k, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		key = k
+	}
+	if key == nil {
+		return nil, false
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, false
+	}
+	return der, true
+}
+
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newCertificate(serial int64, notAfter time.Time) ([]byte, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), key
+}
+
+var _ = Describe("Certificates", func() {
+	var (
+		leaf, ca []byte
+		key      *ecdsa.PrivateKey
+		expiry   time.Time
+	)
+
+	encodeKey := func(pkcs8 bool) []byte {
+		if pkcs8 {
+			der, err := x509.MarshalPKCS8PrivateKey(key)
+			Expect(err).NotTo(HaveOccurred())
+			return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		}
+		der, err := x509.MarshalECPrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	}
+
+	BeforeEach(func() {
+		expiry = time.Now().Add(24 * time.Hour).Truncate(time.Second)
+		leaf, key = newCertificate(1, expiry)
+		ca, _ = newCertificate(2, expiry.Add(time.Hour))
+	})
+
+	It("hashes certificates and keys by identity", func() {
+		hashes := CertificateHashes(map[string][]byte{
+			corev1.TLSCertKey:       append(append([]byte{}, leaf...), ca...),
+			corev1.TLSPrivateKeyKey: encodeKey(false),
+		})
+		reencoded := CertificateHashes(map[string][]byte{
+			corev1.TLSCertKey:       append(append([]byte("# chain\n"), ca...), leaf...),
+			corev1.TLSPrivateKeyKey: encodeKey(true),
+		})
+		Expect(reencoded).To(Equal(hashes))
+
+		renewed, _ := newCertificate(3, expiry)
+		Expect(CertificateHashes(map[string][]byte{corev1.TLSCertKey: renewed})[corev1.TLSCertKey]).
+			NotTo(Equal(hashes[corev1.TLSCertKey]))
+	})
+
+	It("returns the earliest expiry", func() {
+		got, ok := CertificateExpiry(map[string][]byte{corev1.TLSCertKey: append(append([]byte{}, ca...), leaf...)})
+		Expect(ok).To(BeTrue())
+		Expect(got).To(BeTemporally("==", expiry))
+
+		_, ok = CertificateExpiry(map[string][]byte{corev1.TLSCertKey: []byte("not a certificate")})
+		Expect(ok).To(BeFalse())
+	})
+
+	It("keeps the workload hash when a TLS Secret is re-encoded", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "tls", Namespace: "default"},
+			Type:       corev1.SecretTypeTLS,
+			Data:       map[string][]byte{corev1.TLSCertKey: leaf, corev1.TLSPrivateKeyKey: encodeKey(false)},
+		}
+		c := fake.NewClientBuilder().WithObjects(secret).Build()
+		w := newDeployment(nil, corev1.PodSpec{Volumes: []corev1.Volume{{Name: "tls", VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: "tls"},
+		}}}})
+		ctx := context.Background()
+
+		before, err := Hash(ctx, c, w)
+		Expect(err).NotTo(HaveOccurred())
+
+		secret.Data[corev1.TLSPrivateKeyKey] = encodeKey(true)
+		Expect(c.Update(ctx, secret)).To(Succeed())
+		Expect(Hash(ctx, c, w)).To(Equal(before))
+
+		secret.Data[corev1.TLSCertKey], _ = newCertificate(3, expiry)
+		Expect(c.Update(ctx, secret)).To(Succeed())
+		Expect(Hash(ctx, c, w)).NotTo(Equal(before))
+	})
+})
@@ -0,0 +1,170 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Generic describes a workload kind without compiled-in types, e.g. an Argo
+// Rollout, handled through unstructured objects.
+type Generic struct {
+	GVK schema.GroupVersionKind
+	// TemplatePath is the path to the pod template, e.g. ".spec.template".
+	TemplatePath string
+	// SelectorPath is the path to the pod selector, ".spec.selector" when empty.
+	// Both label selectors and plain label maps are understood.
+	SelectorPath string
+}
+
+// generics are the registered generic kinds.
+var generics = map[schema.GroupVersionKind]*genericKind{}
+
+type genericKind struct {
+	kind     string
+	template []string
+	selector []string
+}
+
+// Register adds a generic workload kind to the supported kinds. It must be
+// called before the workloads are watched or listed.
+func Register(g Generic) error {
+	if g.GVK.Kind == "" || g.GVK.Version == "" {
+		return fmt.Errorf("workload kind %q: version and kind are required", g.GVK)
+	}
+	if _, ok := generics[g.GVK]; ok {
+		return fmt.Errorf("workload kind %s is already registered", g.GVK)
+	}
+	template, err := parsePath(g.TemplatePath)
+	if err != nil {
+		return fmt.Errorf("workload kind %s: template path: %w", g.GVK, err)
+	}
+	if g.SelectorPath == "" {
+		g.SelectorPath = ".spec.selector"
+	}
+	selector, err := parsePath(g.SelectorPath)
+	if err != nil {
+		return fmt.Errorf("workload kind %s: selector path: %w", g.GVK, err)
+	}
+
+	gvk := g.GVK
+	generics[gvk] = &genericKind{kind: gvk.Kind, template: template, selector: selector}
+	kinds = append(kinds, kind{
+		object: func() client.Object {
+			u := &unstructured.Unstructured{}
+			u.SetGroupVersionKind(gvk)
+			return u
+		},
+		list: func() client.ObjectList {
+			l := &unstructured.UnstructuredList{}
+			l.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+			return l
+		},
+	})
+	return nil
+}
+
+// parsePath parses a simple JSON path of field names, e.g. "{.spec.template}".
+func parsePath(path string) ([]string, error) {
+	path = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(path), "{"), "}")
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return nil, fmt.Errorf("path is empty")
+	}
+	fields := strings.Split(path, ".")
+	for _, f := range fields {
+		if f == "" || strings.ContainsAny(f, "[]*") {
+			return nil, fmt.Errorf("unsupported path %q, only field names are allowed", path)
+		}
+	}
+	return fields, nil
+}
+
+func newGeneric(u *unstructured.Unstructured) (Workload, bool) {
+	k, ok := generics[u.GroupVersionKind()]
+	if !ok {
+		return nil, false
+	}
+	return &generic{obj: u, kind: k}, true
+}
+
+// generic implements Workload for a registered generic kind.
+type generic struct {
+	obj  *unstructured.Unstructured
+	kind *genericKind
+	spec *corev1.PodSpec
+}
+
+func (g *generic) Object() client.Object { return g.obj }
+
+func (g *generic) Kind() string { return g.kind.kind }
+
+// PodSpec decodes the spec of the pod template once. The result is read-only,
+// changes to it are not written back to the object.
+func (g *generic) PodSpec() *corev1.PodSpec {
+	if g.spec != nil {
+		return g.spec
+	}
+	g.spec = &corev1.PodSpec{}
+	if m, ok, _ := unstructured.NestedMap(g.obj.Object, g.path("spec")...); ok {
+		_ = runtime.DefaultUnstructuredConverter.FromUnstructured(m, g.spec)
+	}
+	return g.spec
+}
+
+// Selector returns nil when the object has no selector at the configured path
+// or it is empty, since an empty selector would match every pod of the namespace.
+func (g *generic) Selector() *metav1.LabelSelector {
+	m, ok, _ := unstructured.NestedMap(g.obj.Object, g.kind.selector...)
+	if !ok {
+		return nil
+	}
+	selector := &metav1.LabelSelector{}
+	_, hasLabels := m["matchLabels"]
+	_, hasExpressions := m["matchExpressions"]
+	if hasLabels || hasExpressions {
+		_ = runtime.DefaultUnstructuredConverter.FromUnstructured(m, selector)
+	} else {
+		selector.MatchLabels, _, _ = unstructured.NestedStringMap(g.obj.Object, g.kind.selector...)
+	}
+	if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		return nil
+	}
+	return selector
+}
+
+func (g *generic) TemplateAnnotation(key string) string {
+	annotations, _, _ := unstructured.NestedStringMap(g.obj.Object, g.path("metadata", "annotations")...)
+	return annotations[key]
+}
+
+func (g *generic) SetTemplateAnnotation(key, value string) {
+	_ = unstructured.SetNestedField(g.obj.Object, value, g.path("metadata", "annotations", key)...)
+}
+
+// path returns the path to a field of the pod template.
+func (g *generic) path(fields ...string) []string {
+	return append(append([]string{}, g.kind.template...), fields...)
+}
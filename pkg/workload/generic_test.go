@@ -0,0 +1,114 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("Generic", func() {
+	rollout := schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout"}
+	config := schema.GroupVersionKind{Group: "apps.openshift.io", Version: "v1", Kind: "DeploymentConfig"}
+
+	BeforeEach(func() {
+		saved := kinds
+		DeferCleanup(func() {
+			delete(generics, rollout)
+			delete(generics, config)
+			kinds = saved
+		})
+	})
+
+	newObject := func(gvk schema.GroupVersionKind, spec map[string]interface{}) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+		u.SetGroupVersionKind(gvk)
+		u.SetName("app")
+		u.SetNamespace("default")
+		return u
+	}
+
+	It("rejects unsupported paths", func() {
+		Expect(Register(Generic{GVK: rollout, TemplatePath: ".spec.templates[0]"})).NotTo(Succeed())
+		Expect(Register(Generic{GVK: rollout})).NotTo(Succeed())
+	})
+
+	It("reads and stamps the pod template at the configured path", func() {
+		Expect(Register(Generic{GVK: rollout, TemplatePath: "{.spec.template}"})).To(Succeed())
+		Expect(Objects()).To(ContainElement(HaveField("Object", HaveKeyWithValue("kind", "Rollout"))))
+
+		u := newObject(rollout, map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "web"}},
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{
+						"name":    "web",
+						"envFrom": []interface{}{map[string]interface{}{"configMapRef": map[string]interface{}{"name": "web"}}},
+					}},
+				},
+			},
+		})
+		w, ok := New(u)
+		Expect(ok).To(BeTrue())
+		Expect(w.Kind()).To(Equal("Rollout"))
+		Expect(w.Selector().MatchLabels).To(HaveKeyWithValue("app", "web"))
+		Expect(IndexSources(u)).To(ConsistOf("ConfigMap/web"))
+
+		w.SetTemplateAnnotation("hash", "abc")
+		Expect(w.TemplateAnnotation("hash")).To(Equal("abc"))
+		value, _, _ := unstructured.NestedString(u.Object, "spec", "template", "metadata", "annotations", "hash")
+		Expect(value).To(Equal("abc"))
+	})
+
+	It("accepts plain label map selectors", func() {
+		Expect(Register(Generic{GVK: config, TemplatePath: ".spec.template"})).To(Succeed())
+
+		w, ok := New(newObject(config, map[string]interface{}{
+			"selector": map[string]interface{}{"app": "web"},
+		}))
+		Expect(ok).To(BeTrue())
+		Expect(w.Selector().MatchLabels).To(HaveKeyWithValue("app", "web"))
+	})
+
+	It("has no selector when the selector path is missing or empty", func() {
+		Expect(Register(Generic{GVK: config, TemplatePath: ".spec.template", SelectorPath: ".spec.podSelector"})).To(Succeed())
+
+		w, ok := New(newObject(config, map[string]interface{}{
+			"selector": map[string]interface{}{"app": "web"},
+		}))
+		Expect(ok).To(BeTrue())
+		Expect(w.Selector()).To(BeNil())
+
+		w, _ = New(newObject(config, map[string]interface{}{
+			"podSelector": map[string]interface{}{"matchLabels": map[string]interface{}{}},
+		}))
+		Expect(w.Selector()).To(BeNil())
+
+		w, _ = New(newObject(config, map[string]interface{}{
+			"podSelector": map[string]interface{}{},
+		}))
+		Expect(w.Selector()).To(BeNil())
+	})
+
+	It("ignores unregistered kinds", func() {
+		_, ok := New(newObject(rollout, nil))
+		Expect(ok).To(BeFalse())
+	})
+})
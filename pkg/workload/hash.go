@@ -0,0 +1,214 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Hash returns the dependency hash of w: a digest over the per-key hashes of
+// every ConfigMap and Secret key its pod template consumes, so a change to an
+// unused key leaves it unchanged. A missing source or key hashes differently
+// from an empty one, so its creation or deletion changes the hash.
+func Hash(ctx context.Context, c client.Reader, w Workload) (string, error) {
+	return digest(References(w), stored(ctx, c, w.Object().GetNamespace()))
+}
+
+// StaticHash returns the hash of the keys w consumes through env or subPath.
+// Running pods never see those keys change, so when it changes the pods must
+// be restarted whatever the reload strategy.
+func StaticHash(ctx context.Context, c client.Reader, w Workload) (string, error) {
+	return digest(staticReferences(w), stored(ctx, c, w.Object().GetNamespace()))
+}
+
+// HashAssuming returns the dependency hash and static hash w would have if
+// the source of the given kind and name had the given key hashes, nil
+// standing for a missing source. The other sources are read from c.
+func HashAssuming(ctx context.Context, c client.Reader, w Workload, kind SourceKind, name string, hashes map[string]string) (string, string, error) {
+	read := stored(ctx, c, w.Object().GetNamespace())
+	lookup := func(ref Reference) (map[string]string, error) {
+		if ref.Kind == kind && ref.Name == name {
+			return hashes, nil
+		}
+		return read(ref)
+	}
+	hash, err := digest(References(w), lookup)
+	if err != nil {
+		return "", "", err
+	}
+	static, err := digest(staticReferences(w), lookup)
+	return hash, static, err
+}
+
+func staticReferences(w Workload) []Reference {
+	var refs []Reference
+	for _, ref := range References(w) {
+		if static, ok := ref.Static(); ok {
+			refs = append(refs, static)
+		}
+	}
+	return refs
+}
+
+// stored returns a lookup of the key hashes of the sources stored in namespace.
+func stored(ctx context.Context, c client.Reader, namespace string) func(Reference) (map[string]string, error) {
+	return func(ref Reference) (map[string]string, error) {
+		return SourceHashes(ctx, c, ref, namespace)
+	}
+}
+
+func digest(refs []Reference, lookup func(Reference) (map[string]string, error)) (string, error) {
+	h := sha256.New()
+	for _, ref := range refs {
+		hashes, err := lookup(ref)
+		if err != nil {
+			return "", err
+		}
+		io.WriteString(h, "\n"+ref.String())
+		if hashes == nil {
+			io.WriteString(h, "\n-")
+			continue
+		}
+		writeKeys(h, hashes, ref.Keys)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SourceData returns the data of the referenced source, or nil when it does not exist.
+func SourceData(ctx context.Context, c client.Reader, ref Reference, namespace string) (map[string][]byte, error) {
+	data, _, err := getSource(ctx, c, ref, namespace)
+	return data, err
+}
+
+// SourceHashes returns the hash of every key of the referenced source, or nil
+// when it does not exist. The certificates and keys of TLS Secrets are hashed
+// by identity, see CertificateHashes.
+func SourceHashes(ctx context.Context, c client.Reader, ref Reference, namespace string) (map[string]string, error) {
+	data, secretType, err := getSource(ctx, c, ref, namespace)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	hashes := KeyHashes(data)
+	if secretType == corev1.SecretTypeTLS {
+		for k, v := range CertificateHashes(data) {
+			hashes[k] = v
+		}
+	}
+	return hashes, nil
+}
+
+// getSource returns the data and, for Secrets, the type of the referenced source.
+func getSource(ctx context.Context, c client.Reader, ref Reference, namespace string) (map[string][]byte, corev1.SecretType, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: ref.Name}
+	switch ref.Kind {
+	case ConfigMap:
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, key, cm); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, "", nil
+			}
+			return nil, "", err
+		}
+		data := make(map[string][]byte, len(cm.Data)+len(cm.BinaryData))
+		for k, v := range cm.Data {
+			data[k] = []byte(v)
+		}
+		for k, v := range cm.BinaryData {
+			data[k] = v
+		}
+		return data, "", nil
+	case Secret:
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, key, secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, "", nil
+			}
+			return nil, "", err
+		}
+		data := make(map[string][]byte, len(secret.Data))
+		for k, v := range secret.Data {
+			data[k] = v
+		}
+		return data, secret.Type, nil
+	}
+	return nil, "", nil
+}
+
+// ChangedKeys returns the keys added, removed or modified between two sets
+// of key hashes.
+func ChangedKeys(old, new map[string]string) []string {
+	var keys []string
+	for k, v := range new {
+		if prev, ok := old[k]; !ok || prev != v {
+			keys = append(keys, k)
+		}
+	}
+	for k := range old {
+		if _, ok := new[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ContentHash returns a digest of the key hashes of a source, or "" when the
+// source does not exist.
+func ContentHash(hashes map[string]string) string {
+	if hashes == nil {
+		return ""
+	}
+	h := sha256.New()
+	writeKeys(h, hashes, nil)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// KeyHashes returns the hash of every key in data.
+func KeyHashes(data map[string][]byte) map[string]string {
+	hashes := make(map[string]string, len(data))
+	for k, v := range data {
+		sum := sha256.Sum256(v)
+		hashes[k] = hex.EncodeToString(sum[:])
+	}
+	return hashes
+}
+
+// writeKeys writes the hashes of keys, or of every key when keys is nil.
+func writeKeys(w io.Writer, hashes map[string]string, keys []string) {
+	if keys == nil {
+		for k := range hashes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+	for _, k := range keys {
+		hash, ok := hashes[k]
+		if !ok {
+			hash = "-"
+		}
+		io.WriteString(w, "\n"+k+"="+hash)
+	}
+}
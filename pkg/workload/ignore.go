@@ -0,0 +1,66 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+)
+
+// managedByLabel names the operator managing an object.
+const managedByLabel = "app.kubernetes.io/managed-by"
+
+// IgnoredChange returns why a change of src attributed to attribution is
+// system noise that must not reload workloads: src is managed by one of the
+// managedBy operators, or the change was made by one of the field managers
+// of managers or of the ignore-managers annotation of src. Patterns of the
+// annotation matching any manager, e.g. "*", are disregarded.
+func IgnoredChange(src client.Object, attribution Attribution, attributed bool, managers, managedBy []string) (string, bool) {
+	if v := src.GetLabels()[managedByLabel]; v != "" && matchesAny(managedBy, v) {
+		return "managed by " + v, true
+	}
+	if !attributed {
+		return "", false
+	}
+	patterns := slices.Clone(managers)
+	if v := src.GetAnnotations()[annotation.IgnoreManagers]; v != "" {
+		for _, p := range strings.Split(v, ",") {
+			// source 의 annotation 으로 모든 변경을 무시할 수는 없음
+			if p = strings.TrimSpace(p); strings.Trim(p, "*?/") != "" {
+				patterns = append(patterns, p)
+			}
+		}
+	}
+	if matchesAny(patterns, attribution.Manager) {
+		return "changed by " + attribution.Manager, true
+	}
+	return "", false
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, err := path.Match(p, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
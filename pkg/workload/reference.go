@@ -0,0 +1,468 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+)
+
+// SourceKind is the kind of object a workload consumes configuration from.
+type SourceKind string
+
+const (
+	ConfigMap SourceKind = "ConfigMap"
+	Secret    SourceKind = "Secret"
+)
+
+// NewSource returns an empty object of the given source kind.
+func NewSource(kind SourceKind) client.Object {
+	if kind == Secret {
+		return &corev1.Secret{}
+	}
+	return &corev1.ConfigMap{}
+}
+
+// NewSourceList returns an empty list of the given source kind.
+func NewSourceList(kind SourceKind) client.ObjectList {
+	if kind == Secret {
+		return &corev1.SecretList{}
+	}
+	return &corev1.ConfigMapList{}
+}
+
+// Consumption is the way a pod template consumes a source.
+type Consumption string
+
+const (
+	// Volume files are updated in running pods by the kubelet.
+	Volume Consumption = "Volume"
+	// SubPath files are copied once when the container starts.
+	SubPath Consumption = "SubPath"
+	// Env variables are resolved once when the container starts.
+	Env Consumption = "Env"
+)
+
+// Reference is a ConfigMap or Secret consumed by a pod template.
+type Reference struct {
+	Kind SourceKind
+	Name string
+	// Optional is true when every consumer of the source tolerates its absence.
+	Optional bool
+	// Keys are the keys consumed from the source, sorted. Nil means every key.
+	Keys []string
+	// Uses are the keys consumed by each consumption, nil meaning every key.
+	Uses map[Consumption][]string
+}
+
+func (r Reference) String() string {
+	return string(r.Kind) + "/" + r.Name
+}
+
+// Static returns the reference restricted to the keys consumed through env
+// or subPath, which running pods never see change, or false if there are none.
+func (r Reference) Static() (Reference, bool) {
+	static := Reference{Kind: r.Kind, Name: r.Name, Optional: r.Optional}
+	sets := []*keySet{}
+	for _, c := range []Consumption{Env, SubPath} {
+		if keys, ok := r.Uses[c]; ok {
+			sets = append(sets, newKeySet(keys))
+		}
+	}
+	if len(sets) == 0 {
+		return static, false
+	}
+	static.Keys = merge(sets...).list()
+	static.Uses = map[Consumption][]string{}
+	for _, c := range []Consumption{Env, SubPath} {
+		if keys, ok := r.Uses[c]; ok {
+			static.Uses[c] = keys
+		}
+	}
+	return static, true
+}
+
+// Projection is a ConfigMap or Secret projected into a volume.
+type Projection struct {
+	Reference Reference
+	// Items maps keys to paths in the volume, every key is projected to its own name when empty.
+	Items []corev1.KeyToPath
+}
+
+// Projections returns the ConfigMaps and Secrets projected into v.
+func Projections(v corev1.Volume) []Projection {
+	optional := func(o *bool) bool { return o != nil && *o }
+	switch {
+	case v.ConfigMap != nil:
+		return []Projection{{Reference{Kind: ConfigMap, Name: v.ConfigMap.Name, Optional: optional(v.ConfigMap.Optional)}, v.ConfigMap.Items}}
+	case v.Secret != nil:
+		return []Projection{{Reference{Kind: Secret, Name: v.Secret.SecretName, Optional: optional(v.Secret.Optional)}, v.Secret.Items}}
+	case v.Projected != nil:
+		var ps []Projection
+		for _, s := range v.Projected.Sources {
+			if s.ConfigMap != nil {
+				ps = append(ps, Projection{Reference{Kind: ConfigMap, Name: s.ConfigMap.Name, Optional: optional(s.ConfigMap.Optional)}, s.ConfigMap.Items})
+			}
+			if s.Secret != nil {
+				ps = append(ps, Projection{Reference{Kind: Secret, Name: s.Secret.Name, Optional: optional(s.Secret.Optional)}, s.Secret.Items})
+			}
+		}
+		return ps
+	}
+	return nil
+}
+
+// subPathKeys returns the keys of p visible through a subPath mount, or
+// false if the mount shows none of them.
+func (p Projection) subPathKeys(subPath string) ([]string, bool) {
+	subPath = strings.Trim(subPath, "/")
+	if len(p.Items) == 0 {
+		if strings.Contains(subPath, "/") {
+			return nil, false
+		}
+		return []string{subPath}, true
+	}
+	var keys []string
+	for _, item := range p.Items {
+		if item.Path == subPath || strings.HasPrefix(item.Path, subPath+"/") {
+			keys = append(keys, item.Key)
+		}
+	}
+	return keys, len(keys) > 0
+}
+
+// keySet is a set of keys, or every key.
+type keySet struct {
+	all  bool
+	keys map[string]bool
+}
+
+func newKeySet(keys []string) *keySet {
+	s := &keySet{keys: map[string]bool{}}
+	s.add(keys)
+	return s
+}
+
+// add adds keys to the set, no keys meaning every key.
+func (s *keySet) add(keys []string) {
+	if len(keys) == 0 {
+		s.all = true
+	}
+	for _, k := range keys {
+		s.keys[k] = true
+	}
+}
+
+// narrow restricts a set of every key to the declared keys.
+func (s *keySet) narrow(declared []string) *keySet {
+	if !s.all || declared == nil {
+		return s
+	}
+	return newKeySet(declared)
+}
+
+// list returns the sorted keys, or nil for every key.
+func (s *keySet) list() []string {
+	if s.all {
+		return nil
+	}
+	keys := make([]string, 0, len(s.keys))
+	for k := range s.keys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func merge(sets ...*keySet) *keySet {
+	merged := &keySet{keys: map[string]bool{}}
+	for _, s := range sets {
+		merged.all = merged.all || s.all
+		for k := range s.keys {
+			merged.keys[k] = true
+		}
+	}
+	return merged
+}
+
+// usage accumulates how a pod template consumes one source.
+type usage struct {
+	required bool
+	uses     map[Consumption]*keySet
+}
+
+type source struct {
+	kind SourceKind
+	name string
+}
+
+type resolver map[source]*usage
+
+// add records a consumption of the source. No keys means the whole source.
+func (res resolver) add(ref Reference, consumption Consumption, keys []string) {
+	if ref.Name == "" {
+		return
+	}
+	src := source{ref.Kind, ref.Name}
+	u, ok := res[src]
+	if !ok {
+		u = &usage{uses: map[Consumption]*keySet{}}
+		res[src] = u
+	}
+	if !ref.Optional {
+		u.required = true
+	}
+	set, ok := u.uses[consumption]
+	if !ok {
+		set = &keySet{keys: map[string]bool{}}
+		u.uses[consumption] = set
+	}
+	set.add(keys)
+}
+
+// References returns the ConfigMaps and Secrets consumed by the pod template
+// of w, sorted by kind and name, with how each of their keys is consumed.
+//
+// Keys are taken from env valueFrom, volume items and subPath mounts. A source
+// consumed as a whole, through envFrom or a volume without items, can be
+// narrowed to the keys listed in the ConfigMapKeys or SecretKeys annotation of w.
+func References(w Workload) []Reference {
+	spec := w.PodSpec()
+	res := resolver{}
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+
+	mounts := map[string][]corev1.VolumeMount{}
+	for _, c := range containers {
+		for _, m := range c.VolumeMounts {
+			mounts[m.Name] = append(mounts[m.Name], m)
+		}
+	}
+	for _, v := range spec.Volumes {
+		for _, p := range Projections(v) {
+			keys := itemKeys(p.Items)
+			if len(mounts[v.Name]) == 0 {
+				res.add(p.Reference, Volume, keys)
+			}
+			for _, m := range mounts[v.Name] {
+				switch {
+				case m.SubPathExpr != "":
+					res.add(p.Reference, SubPath, keys)
+				case m.SubPath != "":
+					if subKeys, ok := p.subPathKeys(m.SubPath); ok {
+						res.add(p.Reference, SubPath, subKeys)
+					}
+				default:
+					res.add(p.Reference, Volume, keys)
+				}
+			}
+		}
+	}
+
+	optional := func(o *bool) bool { return o != nil && *o }
+	for _, c := range containers {
+		for _, e := range c.EnvFrom {
+			if e.ConfigMapRef != nil {
+				res.add(Reference{Kind: ConfigMap, Name: e.ConfigMapRef.Name, Optional: optional(e.ConfigMapRef.Optional)}, Env, nil)
+			}
+			if e.SecretRef != nil {
+				res.add(Reference{Kind: Secret, Name: e.SecretRef.Name, Optional: optional(e.SecretRef.Optional)}, Env, nil)
+			}
+		}
+		for _, e := range c.Env {
+			if e.ValueFrom == nil {
+				continue
+			}
+			if ref := e.ValueFrom.ConfigMapKeyRef; ref != nil {
+				res.add(Reference{Kind: ConfigMap, Name: ref.Name, Optional: optional(ref.Optional)}, Env, []string{ref.Key})
+			}
+			if ref := e.ValueFrom.SecretKeyRef; ref != nil {
+				res.add(Reference{Kind: Secret, Name: ref.Name, Optional: optional(ref.Optional)}, Env, []string{ref.Key})
+			}
+		}
+	}
+
+	annotations := w.Object().GetAnnotations()
+	declared := map[source][]string{}
+	for kind, key := range map[SourceKind]string{ConfigMap: annotation.ConfigMapKeys, Secret: annotation.SecretKeys} {
+		for name, keys := range ParseKeys(annotations[key]) {
+			declared[source{kind, name}] = keys
+		}
+	}
+
+	out := make([]Reference, 0, len(res))
+	for src, u := range res {
+		ref := Reference{Kind: src.kind, Name: src.name, Optional: !u.required, Uses: map[Consumption][]string{}}
+		var sets []*keySet
+		for c, set := range u.uses {
+			set = set.narrow(declared[src])
+			ref.Uses[c] = set.list()
+			sets = append(sets, set)
+		}
+		ref.Keys = merge(sets...).list()
+		out = append(out, ref)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].String() < out[j].String()
+	})
+	return out
+}
+
+// DependsOn reports whether w consumes the source of the given kind and name.
+func DependsOn(w Workload, kind SourceKind, name string) bool {
+	for _, ref := range References(w) {
+		if ref.Kind == kind && ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseKeys parses a key list annotation of the form "name:key,name:key".
+func ParseKeys(value string) map[string][]string {
+	keys := map[string][]string{}
+	for _, entry := range strings.Split(value, ",") {
+		name, key, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || name == "" || key == "" {
+			continue
+		}
+		keys[name] = append(keys[name], key)
+	}
+	return keys
+}
+
+func itemKeys(items []corev1.KeyToPath) []string {
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, item.Key)
+	}
+	return keys
+}
+
+// SourceIndex is the field index of the sources consumed by a workload,
+// with values of the form "Kind/Name".
+const SourceIndex = "reloader.sources"
+
+// IndexSources is the IndexerFunc of SourceIndex.
+func IndexSources(obj client.Object) []string {
+	w, ok := New(obj)
+	if !ok {
+		return nil
+	}
+	refs := References(w)
+	values := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		values = append(values, ref.String())
+	}
+	return values
+}
+
+// PullSecretIndex is the field index of the image pull Secrets listed by a
+// workload or a ServiceAccount.
+const PullSecretIndex = "reloader.pullSecrets"
+
+// ServiceAccountIndex is the field index of the ServiceAccount of a workload.
+const ServiceAccountIndex = "reloader.serviceAccount"
+
+// IndexPullSecrets is the IndexerFunc of PullSecretIndex.
+func IndexPullSecrets(obj client.Object) []string {
+	var secrets []corev1.LocalObjectReference
+	if sa, ok := obj.(*corev1.ServiceAccount); ok {
+		secrets = sa.ImagePullSecrets
+	} else if w, ok := New(obj); ok {
+		secrets = w.PodSpec().ImagePullSecrets
+	}
+	names := make([]string, 0, len(secrets))
+	for _, s := range secrets {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// IndexServiceAccount is the IndexerFunc of ServiceAccountIndex.
+func IndexServiceAccount(obj client.Object) []string {
+	w, ok := New(obj)
+	if !ok {
+		return nil
+	}
+	return []string{serviceAccount(w)}
+}
+
+// PullingWith returns the workloads in namespace whose pods pull images with
+// the named Secret, listed in the pod template or inherited from their
+// ServiceAccount. It relies on PullSecretIndex and ServiceAccountIndex.
+func PullingWith(ctx context.Context, c client.Reader, namespace, name string) ([]Workload, error) {
+	ws, err := List(ctx, c, namespace, client.MatchingFields{PullSecretIndex: name})
+	if err != nil {
+		return nil, err
+	}
+	accounts := &corev1.ServiceAccountList{}
+	if err := c.List(ctx, accounts, client.InNamespace(namespace), client.MatchingFields{PullSecretIndex: name}); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, w := range ws {
+		seen[w.Kind()+"/"+w.Object().GetName()] = true
+	}
+	for _, sa := range accounts.Items {
+		inherited, err := List(ctx, c, namespace, client.MatchingFields{ServiceAccountIndex: sa.Name})
+		if err != nil {
+			return nil, err
+		}
+		for _, w := range inherited {
+			if id := w.Kind() + "/" + w.Object().GetName(); !seen[id] {
+				seen[id] = true
+				ws = append(ws, w)
+			}
+		}
+	}
+	return ws, nil
+}
+
+// PullSecrets returns the references to the image pull Secrets of the pods
+// of w, listed in the pod template or inherited from its ServiceAccount.
+func PullSecrets(ctx context.Context, c client.Reader, w Workload) ([]Reference, error) {
+	secrets := w.PodSpec().ImagePullSecrets
+	sa := &corev1.ServiceAccount{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: w.Object().GetNamespace(), Name: serviceAccount(w)}, sa); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+	} else {
+		secrets = append(append([]corev1.LocalObjectReference{}, secrets...), sa.ImagePullSecrets...)
+	}
+	refs := make([]Reference, 0, len(secrets))
+	for _, s := range secrets {
+		refs = append(refs, Reference{Kind: Secret, Name: s.Name})
+	}
+	return refs, nil
+}
+
+// serviceAccount returns the name of the ServiceAccount of the pods of w.
+func serviceAccount(w Workload) string {
+	if name := w.PodSpec().ServiceAccountName; name != "" {
+		return name
+	}
+	return "default"
+}
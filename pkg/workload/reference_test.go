@@ -0,0 +1,199 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/hotkimho/reloader-server/project/pkg/annotation"
+)
+
+func newDeployment(annotations map[string]string, spec corev1.PodSpec) Workload {
+	d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Annotations: annotations}}
+	d.Spec.Template.Spec = spec
+	w, _ := New(d)
+	return w
+}
+
+func envFrom(name string) corev1.EnvFromSource {
+	return corev1.EnvFromSource{ConfigMapRef: &corev1.ConfigMapEnvSource{
+		LocalObjectReference: corev1.LocalObjectReference{Name: name},
+	}}
+}
+
+func envKey(name, key string) corev1.EnvVar {
+	return corev1.EnvVar{Name: key, ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: name},
+		Key:                  key,
+	}}}
+}
+
+var _ = Describe("References", func() {
+	It("tracks the keys consumed through env and volume items", func() {
+		optional := true
+		w := newDeployment(nil, corev1.PodSpec{
+			Volumes: []corev1.Volume{{Name: "v", VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "shared"},
+					Items:                []corev1.KeyToPath{{Key: "app.yaml", Path: "app.yaml"}},
+				},
+			}}, {Name: "s", VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: "tls", Optional: &optional},
+			}}},
+			Containers: []corev1.Container{{Env: []corev1.EnvVar{envKey("shared", "LOG_LEVEL")}}},
+		})
+
+		Expect(References(w)).To(Equal([]Reference{
+			{Kind: ConfigMap, Name: "shared", Keys: []string{"LOG_LEVEL", "app.yaml"}, Uses: map[Consumption][]string{
+				Volume: {"app.yaml"},
+				Env:    {"LOG_LEVEL"},
+			}},
+			{Kind: Secret, Name: "tls", Optional: true, Uses: map[Consumption][]string{Volume: nil}},
+		}))
+	})
+
+	It("consumes every key through envFrom", func() {
+		w := newDeployment(nil, corev1.PodSpec{Containers: []corev1.Container{{
+			EnvFrom: []corev1.EnvFromSource{envFrom("shared")},
+			Env:     []corev1.EnvVar{envKey("shared", "LOG_LEVEL")},
+		}}})
+
+		Expect(References(w)).To(Equal([]Reference{{Kind: ConfigMap, Name: "shared", Uses: map[Consumption][]string{Env: nil}}}))
+	})
+
+	It("narrows whole sources to the annotated keys", func() {
+		w := newDeployment(map[string]string{annotation.ConfigMapKeys: "shared:LOG_LEVEL, shared:FEATURES, other:X"},
+			corev1.PodSpec{Containers: []corev1.Container{{EnvFrom: []corev1.EnvFromSource{envFrom("shared")}}}})
+
+		Expect(References(w)).To(Equal([]Reference{
+			{Kind: ConfigMap, Name: "shared", Keys: []string{"FEATURES", "LOG_LEVEL"}, Uses: map[Consumption][]string{
+				Env: {"FEATURES", "LOG_LEVEL"},
+			}},
+		}))
+	})
+
+	It("classifies subPath mounts", func() {
+		w := newDeployment(nil, corev1.PodSpec{
+			Volumes: []corev1.Volume{{Name: "conf", VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "shared"}},
+			}}},
+			Containers: []corev1.Container{{VolumeMounts: []corev1.VolumeMount{
+				{Name: "conf", MountPath: "/etc/app/app.yaml", SubPath: "app.yaml"},
+				{Name: "conf", MountPath: "/etc/shared"},
+			}}},
+		})
+
+		refs := References(w)
+		Expect(refs).To(HaveLen(1))
+		Expect(refs[0].Uses).To(Equal(map[Consumption][]string{SubPath: {"app.yaml"}, Volume: nil}))
+
+		static, ok := refs[0].Static()
+		Expect(ok).To(BeTrue())
+		Expect(static.Keys).To(Equal([]string{"app.yaml"}))
+	})
+})
+
+var _ = Describe("Hash", func() {
+	It("changes only when a consumed key changes", func() {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "default"},
+			Data:       map[string]string{"LOG_LEVEL": "info", "DB_URL": "postgres://a"},
+		}
+		c := fake.NewClientBuilder().WithObjects(cm).Build()
+		w := newDeployment(nil, corev1.PodSpec{Containers: []corev1.Container{{
+			Env: []corev1.EnvVar{envKey("shared", "LOG_LEVEL")},
+		}}})
+		ctx := context.Background()
+
+		before, err := Hash(ctx, c, w)
+		Expect(err).NotTo(HaveOccurred())
+
+		cm.Data["DB_URL"] = "postgres://b"
+		Expect(c.Update(ctx, cm)).To(Succeed())
+		Expect(Hash(ctx, c, w)).To(Equal(before))
+
+		cm.Data["LOG_LEVEL"] = "debug"
+		Expect(c.Update(ctx, cm)).To(Succeed())
+		Expect(Hash(ctx, c, w)).NotTo(Equal(before))
+	})
+
+	It("tracks keys consumed through env in the static hash", func() {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "default"},
+			Data:       map[string]string{"LOG_LEVEL": "info", "app.yaml": "a: 1"},
+		}
+		c := fake.NewClientBuilder().WithObjects(cm).Build()
+		w := newDeployment(nil, corev1.PodSpec{
+			Volumes: []corev1.Volume{{Name: "conf", VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "shared"}},
+			}}},
+			Containers: []corev1.Container{{
+				Env:          []corev1.EnvVar{envKey("shared", "LOG_LEVEL")},
+				VolumeMounts: []corev1.VolumeMount{{Name: "conf", MountPath: "/etc/app"}},
+			}},
+		})
+		ctx := context.Background()
+
+		before, err := StaticHash(ctx, c, w)
+		Expect(err).NotTo(HaveOccurred())
+
+		cm.Data["app.yaml"] = "a: 2"
+		Expect(c.Update(ctx, cm)).To(Succeed())
+		Expect(StaticHash(ctx, c, w)).To(Equal(before))
+
+		cm.Data["LOG_LEVEL"] = "debug"
+		Expect(c.Update(ctx, cm)).To(Succeed())
+		Expect(StaticHash(ctx, c, w)).NotTo(Equal(before))
+	})
+	It("finds the workloads pulling with a Secret directly or through their ServiceAccount", func() {
+		direct := newDeployment(nil, corev1.PodSpec{ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}}})
+		inherited := newDeployment(nil, corev1.PodSpec{ServiceAccountName: "builder"})
+		inherited.Object().SetName("builder")
+		other := newDeployment(nil, corev1.PodSpec{})
+		other.Object().SetName("other")
+		sa := &corev1.ServiceAccount{
+			ObjectMeta:       metav1.ObjectMeta{Name: "builder", Namespace: "default"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+		}
+		b := fake.NewClientBuilder().WithObjects(direct.Object(), inherited.Object(), other.Object(), sa).
+			WithIndex(&corev1.ServiceAccount{}, PullSecretIndex, IndexPullSecrets)
+		for _, obj := range Objects() {
+			b = b.WithIndex(obj, PullSecretIndex, IndexPullSecrets).WithIndex(obj, ServiceAccountIndex, IndexServiceAccount)
+		}
+		c := b.Build()
+
+		ws, err := PullingWith(context.Background(), c, "default", "registry")
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, w := range ws {
+			names = append(names, w.Object().GetName())
+		}
+		Expect(names).To(ConsistOf("app", "builder"))
+
+		refs, err := PullSecrets(context.Background(), c, inherited)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refs).To(ConsistOf(Reference{Kind: Secret, Name: "registry"}))
+	})
+})
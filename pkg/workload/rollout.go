@@ -0,0 +1,73 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewObject returns an empty object of the workload kind with the given
+// name, e.g. Deployment.
+func NewObject(kindName string) (client.Object, bool) {
+	for _, k := range kinds {
+		obj := k.object()
+		if w, ok := New(obj); ok && w.Kind() == kindName {
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+// RolloutStatus reports whether the current pod template of w runs on every
+// replica, or why its rollout failed. Kinds whose rollout cannot be observed
+// are reported as rolled out.
+func RolloutStatus(w Workload) (done bool, failure string) {
+	switch o := w.Object().(type) {
+	case *appsv1.Deployment:
+		for _, c := range o.Status.Conditions {
+			if c.Type == appsv1.DeploymentProgressing && c.Status == corev1.ConditionFalse &&
+				c.Reason == "ProgressDeadlineExceeded" {
+				return false, c.Message
+			}
+		}
+		replicas := replicasOf(o.Spec.Replicas)
+		return o.Status.ObservedGeneration >= o.Generation &&
+			o.Status.UpdatedReplicas == replicas &&
+			o.Status.Replicas == replicas &&
+			o.Status.AvailableReplicas == replicas, ""
+	case *appsv1.StatefulSet:
+		replicas := replicasOf(o.Spec.Replicas)
+		return o.Status.ObservedGeneration >= o.Generation &&
+			o.Status.UpdatedReplicas == replicas &&
+			o.Status.ReadyReplicas == replicas, ""
+	case *appsv1.DaemonSet:
+		return o.Status.ObservedGeneration >= o.Generation &&
+			o.Status.UpdatedNumberScheduled == o.Status.DesiredNumberScheduled &&
+			o.Status.NumberAvailable == o.Status.DesiredNumberScheduled, ""
+	}
+	return true, ""
+}
+
+// replicasOf returns the desired replicas, which default to one.
+func replicasOf(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
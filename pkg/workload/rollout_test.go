@@ -0,0 +1,65 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("Rollout", func() {
+	It("creates empty objects by kind", func() {
+		obj, ok := NewObject("StatefulSet")
+		Expect(ok).To(BeTrue())
+		Expect(obj).To(BeAssignableToTypeOf(&appsv1.StatefulSet{}))
+
+		_, ok = NewObject("ReplicaSet")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("waits for every replica of a Deployment to be updated and available", func() {
+		d := &appsv1.Deployment{}
+		d.Generation = 2
+		d.Status = appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 1, AvailableReplicas: 2}
+		w, _ := New(d)
+		Expect(RolloutStatus(w)).To(BeFalse())
+
+		d.Status = appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}
+		Expect(RolloutStatus(w)).To(BeTrue())
+	})
+
+	It("reports Deployments past their progress deadline", func() {
+		d := &appsv1.Deployment{}
+		d.Status.Conditions = []appsv1.DeploymentCondition{{
+			Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse,
+			Reason: "ProgressDeadlineExceeded", Message: `ReplicaSet "web-5d8f" has timed out progressing.`,
+		}}
+		w, _ := New(d)
+		done, failure := RolloutStatus(w)
+		Expect(done).To(BeFalse())
+		Expect(failure).To(ContainSubstring("timed out"))
+	})
+
+	It("treats kinds without rollout status as rolled out", func() {
+		w, _ := New(&batchv1.CronJob{})
+		Expect(RolloutStatus(w)).To(BeTrue())
+	})
+})
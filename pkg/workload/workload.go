@@ -0,0 +1,209 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workload adapts the pod-template owning resources the reloader can restart.
+package workload
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Workload is an object owning a pod template.
+type Workload interface {
+	// Object returns the underlying object, suitable for the client.
+	Object() client.Object
+	// Kind returns the kind of the workload, e.g. Deployment.
+	Kind() string
+	// PodSpec returns the spec of the pod template.
+	PodSpec() *corev1.PodSpec
+	// Selector returns the label selector of the pods of the workload, nil
+	// when the workload does not own pods directly.
+	Selector() *metav1.LabelSelector
+	// TemplateAnnotation returns an annotation of the pod template.
+	TemplateAnnotation(key string) string
+	// SetTemplateAnnotation sets an annotation on the pod template.
+	SetTemplateAnnotation(key, value string)
+}
+
+// CronJob is the kind of CronJobs, whose job template is updated so that
+// future Jobs pick up the change.
+const CronJob = "CronJob"
+
+// kind describes one supported workload kind.
+type kind struct {
+	object func() client.Object
+	list   func() client.ObjectList
+}
+
+var kinds = []kind{
+	{
+		object: func() client.Object { return &appsv1.Deployment{} },
+		list:   func() client.ObjectList { return &appsv1.DeploymentList{} },
+	},
+	{
+		object: func() client.Object { return &appsv1.StatefulSet{} },
+		list:   func() client.ObjectList { return &appsv1.StatefulSetList{} },
+	},
+	{
+		object: func() client.Object { return &appsv1.DaemonSet{} },
+		list:   func() client.ObjectList { return &appsv1.DaemonSetList{} },
+	},
+	{
+		object: func() client.Object { return &batchv1.CronJob{} },
+		list:   func() client.ObjectList { return &batchv1.CronJobList{} },
+	},
+}
+
+// Objects returns an empty object of every supported workload kind, e.g. for watches.
+func Objects() []client.Object {
+	objs := make([]client.Object, 0, len(kinds))
+	for _, k := range kinds {
+		objs = append(objs, k.object())
+	}
+	return objs
+}
+
+// New wraps obj as a Workload. It returns false when obj is not a supported kind.
+func New(obj client.Object) (Workload, bool) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return &templated{o, "Deployment", &o.Spec.Template, o.Spec.Selector}, true
+	case *appsv1.StatefulSet:
+		return &templated{o, "StatefulSet", &o.Spec.Template, o.Spec.Selector}, true
+	case *appsv1.DaemonSet:
+		return &templated{o, "DaemonSet", &o.Spec.Template, o.Spec.Selector}, true
+	case *batchv1.CronJob:
+		// Jobs are created from the job template, the CronJob itself has no pods
+		return &templated{o, CronJob, &o.Spec.JobTemplate.Spec.Template, nil}, true
+	case *unstructured.Unstructured:
+		return newGeneric(o)
+	}
+	return nil, false
+}
+
+// List returns the workloads of every supported kind in namespace.
+func List(ctx context.Context, c client.Reader, namespace string, opts ...client.ListOption) ([]Workload, error) {
+	var ws []Workload
+	for _, k := range kinds {
+		list := k.list()
+		if err := c.List(ctx, list, append([]client.ListOption{client.InNamespace(namespace)}, opts...)...); err != nil {
+			return nil, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if w, ok := New(item.(client.Object)); ok {
+				ws = append(ws, w)
+			}
+		}
+	}
+	return ws, nil
+}
+
+// templated implements Workload for the typed apps/v1 resources.
+type templated struct {
+	obj      client.Object
+	kind     string
+	template *corev1.PodTemplateSpec
+	selector *metav1.LabelSelector
+}
+
+func (t *templated) Object() client.Object { return t.obj }
+
+func (t *templated) Kind() string { return t.kind }
+
+func (t *templated) PodSpec() *corev1.PodSpec { return &t.template.Spec }
+
+func (t *templated) Selector() *metav1.LabelSelector { return t.selector }
+
+func (t *templated) TemplateAnnotation(key string) string {
+	return t.template.Annotations[key]
+}
+
+func (t *templated) SetTemplateAnnotation(key, value string) {
+	if t.template.Annotations == nil {
+		t.template.Annotations = map[string]string{}
+	}
+	t.template.Annotations[key] = value
+}
+
+// Dormant reports whether w runs no pods to reload, because its rollout is
+// paused or it is scaled to zero, with the reason.
+func Dormant(w Workload) (string, bool) {
+	var (
+		paused   bool
+		replicas *int32
+	)
+	switch o := w.Object().(type) {
+	case *appsv1.Deployment:
+		paused, replicas = o.Spec.Paused, o.Spec.Replicas
+	case *appsv1.StatefulSet:
+		replicas = o.Spec.Replicas
+	case *unstructured.Unstructured:
+		paused, _, _ = unstructured.NestedBool(o.Object, "spec", "paused")
+		if n, ok, _ := unstructured.NestedInt64(o.Object, "spec", "replicas"); ok {
+			r := int32(n)
+			replicas = &r
+		}
+	}
+	switch {
+	case paused:
+		return "rollout is paused", true
+	case replicas != nil && *replicas == 0:
+		return "scaled to zero", true
+	}
+	return "", false
+}
+
+// Pods returns the pods of w.
+func Pods(ctx context.Context, c client.Reader, w Workload) ([]corev1.Pod, error) {
+	if w.Selector() == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(w.Selector())
+	if err != nil {
+		return nil, err
+	}
+
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(w.Object().GetNamespace()), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+// Ready reports whether pod is ready and not terminating.
+func Ready(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}